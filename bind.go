@@ -0,0 +1,268 @@
+package horo
+
+import (
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// MaxMultipartMemory is maximum memory used to parse a multipart body.
+	MaxMultipartMemory = 32 << 20
+)
+
+var (
+	// ErrBindPointer is thrown if Bind is not given a non-nil pointer.
+	ErrBindPointer = errors.New("bind requires a non-nil pointer")
+
+	bindLabel = map[string]string{
+		"param": "path parameter",
+		"query": "query parameter",
+		"form":  "form field",
+	}
+
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+	fileHeaderType      = reflect.TypeOf((*multipart.FileHeader)(nil))
+)
+
+// Bind binds path params, query values and the request body into v.
+//
+// Path params and query values are bound to struct fields tagged
+// `param:"name"` and `query:"name"`. The body is decoded by Content-Type:
+// JSON and XML use encoding/json and encoding/xml, form and multipart
+// bodies are bound to fields tagged `form:"name"`. Multipart files are
+// bound to *multipart.FileHeader or []*multipart.FileHeader fields.
+//
+// Later sources override earlier ones. Invalid values return a 400
+// *HTTPError, and an unsupported Content-Type returns a 415 *HTTPError.
+// The bound value is validated by Horo.Validator if set.
+func Bind(c context.Context, v interface{}) error {
+	hc := fromCtx(c)
+	if hc == nil {
+		return ErrNotContext
+	}
+
+	if rv := reflect.ValueOf(v); rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return ErrBindPointer
+	}
+
+	if len(hc.ps) > 0 {
+		ps := make(map[string][]string, len(hc.ps))
+		for _, p := range hc.ps {
+			ps[p.Key] = []string{p.Value}
+		}
+		if err := bindData(v, "param", ps, nil); err != nil {
+			return err
+		}
+	}
+
+	if err := bindData(v, "query", hc.r.URL.Query(), nil); err != nil {
+		return err
+	}
+
+	if err := bindBody(hc.r, v); err != nil {
+		return err
+	}
+
+	if hc.h.Validator != nil {
+		return hc.h.Validator.Validate(v)
+	}
+	return nil
+}
+
+func bindBody(r *http.Request, v interface{}) error {
+	if r.Body == nil || r.ContentLength == 0 {
+		return nil
+	}
+
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch {
+	case ct == "application/json" || strings.HasSuffix(ct, "+json"):
+		if err := json.NewDecoder(r.Body).Decode(v); err != nil && err != io.EOF {
+			return &HTTPError{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("invalid JSON body: %v", err),
+			}
+		}
+	case ct == "application/xml" || ct == "text/xml" || strings.HasSuffix(ct, "+xml"):
+		if err := xml.NewDecoder(r.Body).Decode(v); err != nil && err != io.EOF {
+			return &HTTPError{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("invalid XML body: %v", err),
+			}
+		}
+	case ct == "application/x-www-form-urlencoded":
+		if err := r.ParseForm(); err != nil {
+			return &HTTPError{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("invalid form body: %v", err),
+			}
+		}
+		return bindData(v, "form", r.PostForm, nil)
+	case ct == "multipart/form-data":
+		if err := r.ParseMultipartForm(MaxMultipartMemory); err != nil {
+			return &HTTPError{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("invalid multipart body: %v", err),
+			}
+		}
+		return bindData(v, "form", r.MultipartForm.Value, r.MultipartForm.File)
+	default:
+		return &HTTPError{
+			Code:    http.StatusUnsupportedMediaType,
+			Message: http.StatusText(http.StatusUnsupportedMediaType),
+		}
+	}
+	return nil
+}
+
+func bindData(v interface{}, tag string, data map[string][]string, files map[string][]*multipart.FileHeader) error {
+	rv := reflect.ValueOf(v).Elem()
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	return bindStruct(rv, tag, data, files)
+}
+
+func bindStruct(rv reflect.Value, tag string, data map[string][]string, files map[string][]*multipart.FileHeader) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		fv := rv.Field(i)
+
+		name := strings.Split(sf.Tag.Get(tag), ",")[0]
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			if sf.Anonymous && fv.Kind() == reflect.Struct {
+				if err := bindStruct(fv, tag, data, files); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if !fv.CanSet() {
+			continue
+		}
+
+		if fhs, ok := files[name]; ok && setFiles(fv, fhs) {
+			continue
+		}
+
+		vs, ok := data[name]
+		if !ok || len(vs) == 0 {
+			continue
+		}
+
+		if err := setField(fv, vs); err != nil {
+			if ne, ok := err.(*strconv.NumError); ok {
+				err = ne.Err
+			}
+			return &HTTPError{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("invalid %s %q: %v", bindLabel[tag], name, err),
+			}
+		}
+	}
+	return nil
+}
+
+func setFiles(fv reflect.Value, fhs []*multipart.FileHeader) bool {
+	switch {
+	case fv.Type() == fileHeaderType:
+		if len(fhs) > 0 {
+			fv.Set(reflect.ValueOf(fhs[0]))
+		}
+		return true
+	case fv.Kind() == reflect.Slice && fv.Type().Elem() == fileHeaderType:
+		fv.Set(reflect.ValueOf(fhs))
+		return true
+	}
+	return false
+}
+
+func setField(fv reflect.Value, vs []string) error {
+	if fv.Kind() == reflect.Slice && !fv.Addr().Type().Implements(textUnmarshalerType) {
+		s := reflect.MakeSlice(fv.Type(), len(vs), len(vs))
+		for i, v := range vs {
+			if err := setValue(s.Index(i), v); err != nil {
+				return err
+			}
+		}
+		fv.Set(s)
+		return nil
+	}
+	return setValue(fv, vs[0])
+}
+
+func setValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Ptr {
+		p := reflect.New(v.Type().Elem())
+		if err := setValue(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
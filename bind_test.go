@@ -0,0 +1,157 @@
+package horo
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+	"golang.org/x/net/context"
+)
+
+type bindUser struct {
+	ID      int64         `param:"id" json:"-" xml:"-"`
+	Name    string        `json:"name" xml:"name" form:"name"`
+	Tags    []string      `query:"tag" json:"tags" xml:"tag" form:"tag"`
+	Page    *int          `query:"page" json:"-" xml:"-"`
+	Active  bool          `form:"active" json:"active" xml:"active"`
+	Timeout time.Duration `query:"timeout" json:"-" xml:"-"`
+}
+
+func bindRequest(t *testing.T, method, path, contentType string, body io.Reader) (*bindUser, *httptest.ResponseRecorder) {
+	u := new(bindUser)
+	h := New()
+	h.Handle(method, "/users/:id", func(c context.Context) error {
+		if err := Bind(c, u); err != nil {
+			return err
+		}
+		return NoContent(c, http.StatusNoContent)
+	})
+
+	r, _ := http.NewRequest(method, path, body)
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	return u, w
+}
+
+func TestBind(t *testing.T) {
+	page := 2
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+		want        *bindUser
+	}{
+		{
+			name: "query",
+			path: "/users/1?tag=a&tag=b&page=2&timeout=1s",
+			want: &bindUser{ID: 1, Tags: []string{"a", "b"}, Page: &page, Timeout: time.Second},
+		},
+		{
+			name:        "json",
+			path:        "/users/2?tag=a",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"name":"gopher","tags":["x"],"active":true}`,
+			want:        &bindUser{ID: 2, Name: "gopher", Tags: []string{"x"}, Active: true},
+		},
+		{
+			name:        "xml",
+			path:        "/users/3",
+			contentType: "application/xml",
+			body:        `<user><name>gopher</name><tag>x</tag><tag>y</tag></user>`,
+			want:        &bindUser{ID: 3, Name: "gopher", Tags: []string{"x", "y"}},
+		},
+		{
+			name:        "form",
+			path:        "/users/4",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "name=gopher&active=true&tag=z",
+			want:        &bindUser{ID: 4, Name: "gopher", Tags: []string{"z"}, Active: true},
+		},
+	}
+
+	for _, tt := range tests {
+		u, w := bindRequest(t, "POST", tt.path, tt.contentType, strings.NewReader(tt.body))
+
+		if code, want := w.Code, http.StatusNoContent; code != want {
+			t.Errorf("%s: w.Code = %v; want %v (%s)", tt.name, code, want, w.Body)
+		}
+
+		if diff := pretty.Compare(u, tt.want); diff != "" {
+			t.Errorf("%s: diff:\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestBindMultipart(t *testing.T) {
+	type upload struct {
+		Name string                `form:"name"`
+		File *multipart.FileHeader `form:"file"`
+	}
+
+	var u upload
+	h := New()
+	h.POST("/", func(c context.Context) error {
+		return Bind(c, &u)
+	})
+
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	mw.WriteField("name", "gopher")
+	fw, _ := mw.CreateFormFile("file", "gopher.txt")
+	fw.Write([]byte("hello"))
+	mw.Close()
+
+	r, _ := http.NewRequest("POST", "/", body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if u.Name != "gopher" {
+		t.Errorf("Name = %v; want gopher", u.Name)
+	}
+
+	if u.File == nil || u.File.Filename != "gopher.txt" {
+		t.Errorf("File = %v; want gopher.txt", u.File)
+	}
+}
+
+func TestBindError(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+		code        int
+		msg         string
+	}{
+		{"param", "/users/abc", "", "", 400, `invalid path parameter "id": invalid syntax`},
+		{"query", "/users/1?page=x", "", "", 400, `invalid query parameter "page": invalid syntax`},
+		{"json", "/users/1", "application/json", "{", 400, "invalid JSON body: unexpected EOF"},
+		{"media type", "/users/1", "application/octet-stream", "x", 415, "Unsupported Media Type"},
+	}
+
+	for _, tt := range tests {
+		_, w := bindRequest(t, "POST", tt.path, tt.contentType, strings.NewReader(tt.body))
+
+		if code := w.Code; code != tt.code {
+			t.Errorf("%s: w.Code = %v; want %v", tt.name, code, tt.code)
+		}
+
+		if body := w.Body.String(); body != tt.msg {
+			t.Errorf("%s: w.Body = %v; want %v", tt.name, body, tt.msg)
+		}
+	}
+}
@@ -1,14 +1,25 @@
 package horo
 
 import (
+	"bytes"
 	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	uuid "github.com/satori/go.uuid"
 	"golang.org/x/net/context"
 )
 
+const maxPooledBuffer = 64 << 10
+
 type (
 	// RequestIDGenerator is generate requestID method interface.
 	RequestIDGenerator interface {
@@ -23,8 +34,17 @@ type (
 		r     *http.Request
 		ps    httprouter.Params
 		reqID string
+		query url.Values
+		done  []func()
+
+		pageCode int
+		pageErr  error
 	}
 
+	// JSONIter calls yield for each value to encode.
+	// It returns the error of yield.
+	JSONIter func(yield func(v interface{}) error) error
+
 	ctxkey struct {
 		name string
 	}
@@ -37,6 +57,14 @@ var (
 	DefaultRequestIDGenerator RequestIDGenerator = &reqGen{}
 
 	ctxKey = &ctxkey{"horo ctx"}
+
+	bufPool = sync.Pool{
+		New: func() interface{} {
+			return new(bytes.Buffer)
+		},
+	}
+
+	jsonpCallback = regexp.MustCompile(`^[a-zA-Z_$][0-9a-zA-Z_$]*(\.[a-zA-Z_$][0-9a-zA-Z_$]*)*$`)
 )
 
 func fromCtx(c context.Context) (ctx *horoCtx) {
@@ -56,6 +84,21 @@ func (c *horoCtx) Reset(rw http.ResponseWriter, r *http.Request, ps httprouter.P
 	c.r = r
 	c.ps = ps
 	c.reqID = ""
+	c.query = nil
+	c.done = nil
+	c.pageCode = 0
+	c.pageErr = nil
+}
+
+// onFinish registers f called after the request is served.
+func (c *horoCtx) onFinish(f func()) {
+	c.done = append(c.done, f)
+}
+
+func (c *horoCtx) finish() {
+	for _, f := range c.done {
+		f()
+	}
 }
 
 // Param returns url param.
@@ -68,6 +111,90 @@ func Param(c context.Context, name string) (v string) {
 	return
 }
 
+// ParamInt returns url param as int.
+func ParamInt(c context.Context, name string) (int, error) {
+	n, err := strconv.Atoi(Param(c, name))
+	if err != nil {
+		return 0, paramError("param", name)
+	}
+	return n, nil
+}
+
+// ParamInt64 returns url param as int64.
+func ParamInt64(c context.Context, name string) (int64, error) {
+	n, err := strconv.ParseInt(Param(c, name), 10, 64)
+	if err != nil {
+		return 0, paramError("param", name)
+	}
+	return n, nil
+}
+
+// ParamUUID returns url param as UUID.
+func ParamUUID(c context.Context, name string) (uuid.UUID, error) {
+	id, err := uuid.FromString(Param(c, name))
+	if err != nil {
+		return uuid.Nil, paramError("param", name)
+	}
+	return id, nil
+}
+
+// QueryParams returns url query values.
+func QueryParams(c context.Context) (v url.Values) {
+	if c := fromCtx(c); c != nil {
+		if c.query == nil {
+			c.query = c.r.URL.Query()
+		}
+		v = c.query
+	}
+	return
+}
+
+// QueryParam returns url query value.
+func QueryParam(c context.Context, name string) string {
+	return QueryParams(c).Get(name)
+}
+
+// QueryDefault returns url query value, or def if it is empty.
+func QueryDefault(c context.Context, name, def string) string {
+	if v := QueryParam(c, name); v != "" {
+		return v
+	}
+	return def
+}
+
+// QueryBool returns url query value as bool. It is false if empty.
+func QueryBool(c context.Context, name string) (bool, error) {
+	v := QueryParam(c, name)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, paramError("query", name)
+	}
+	return b, nil
+}
+
+// QueryTime returns url query value parsed with layout. It is zero time if empty.
+func QueryTime(c context.Context, name, layout string) (time.Time, error) {
+	v := QueryParam(c, name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(layout, v)
+	if err != nil {
+		return time.Time{}, paramError("query", name)
+	}
+	return t, nil
+}
+
+func paramError(kind, name string) *HTTPError {
+	return &HTTPError{
+		Code:    http.StatusBadRequest,
+		Message: fmt.Sprintf("invalid %s %q", bindLabel[kind], name),
+	}
+}
+
 // RequestID returns request id from context.
 func RequestID(ctx context.Context) (id string) {
 	if c := fromCtx(ctx); c != nil {
@@ -123,21 +250,178 @@ func HTML(c context.Context, code int, html string) (err error) {
 }
 
 // JSON send a JSON response.
-func JSON(c context.Context, code int, i interface{}) (err error) {
+// With Horo.Debug, the ?pretty query indents the response.
+func JSON(c context.Context, code int, i interface{}) error {
+	if hc := fromCtx(c); hc != nil && hc.h.Debug {
+		if _, ok := QueryParams(c)["pretty"]; ok {
+			return JSONPretty(c, code, i, "  ")
+		}
+	}
+	return jsonBlob(c, code, MIMEApplicationJSONCharsetUTF8, i, "")
+}
+
+// JSONPretty send an indented JSON response.
+func JSONPretty(c context.Context, code int, i interface{}, indent string) error {
+	return jsonBlob(c, code, MIMEApplicationJSONCharsetUTF8, i, indent)
+}
+
+func jsonBlob(c context.Context, code int, contentType string, i interface{}, indent string) error {
+	b := getBuffer()
+	defer putBuffer(b)
+	if err := encodeJSONTo(b, i, indent); err != nil {
+		return err
+	}
+	return writeBuffer(c, code, contentType, b)
+}
+
+// encodeJSONTo encodes i into b without the trailing newline.
+func encodeJSONTo(b *bytes.Buffer, i interface{}, indent string) error {
+	enc := json.NewEncoder(b)
+	if indent != "" {
+		enc.SetIndent("", indent)
+	}
+	if err := enc.Encode(i); err != nil {
+		return err
+	}
+	b.Truncate(b.Len() - 1)
+	return nil
+}
+
+// JSONP send a JSONP response calling callback.
+// It returns a 400 *HTTPError if callback is not a valid JavaScript identifier.
+func JSONP(c context.Context, code int, callback string, i interface{}) error {
+	if !jsonpCallback.MatchString(callback) {
+		return &HTTPError{
+			Code:    http.StatusBadRequest,
+			Message: "invalid JSONP callback",
+		}
+	}
+	b := getBuffer()
+	defer putBuffer(b)
+	b.WriteString("/**/")
+	b.WriteString(callback)
+	b.WriteByte('(')
+	if err := encodeJSONTo(b, i, ""); err != nil {
+		return err
+	}
+	b.WriteString(");")
+	return writeBuffer(c, code, "application/javascript; charset=utf-8", b)
+}
+
+// JSONStream send a JSON array of the values yielded by iter.
+// The response is committed with the first value, so an error before it
+// is handled as usual and an error after it leaves a committed response.
+func JSONStream(c context.Context, code int, iter JSONIter) error {
+	return jsonStream(c, code, MIMEApplicationJSONCharsetUTF8, iter, true)
+}
+
+// NDJSON send newline delimited JSON of the values yielded by iter.
+func NDJSON(c context.Context, code int, iter JSONIter) error {
+	return jsonStream(c, code, "application/x-ndjson", iter, false)
+}
+
+func jsonStream(c context.Context, code int, contentType string, iter JSONIter, array bool) error {
+	hc := fromCtx(c)
+	if hc == nil {
+		return ErrNotContext
+	}
+
+	n := 0
+	// start commits the response, so an error before the first value
+	// leaves it uncommitted for ErrorHandler.
+	start := func() error {
+		hc.w.Header().Set("Content-Type", contentType)
+		hc.w.WriteHeader(code)
+		if array {
+			_, err := hc.w.Write([]byte{'['})
+			return err
+		}
+		return nil
+	}
+	err := iter(func(v interface{}) error {
+		b := getBuffer()
+		defer putBuffer(b)
+		if err := json.NewEncoder(b).Encode(v); err != nil {
+			return err
+		}
+		if n == 0 {
+			if err := start(); err != nil {
+				return err
+			}
+		} else if array {
+			if _, err := hc.w.Write([]byte{','}); err != nil {
+				return err
+			}
+		}
+		n++
+		_, err := hc.w.Write(b.Bytes())
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		if err := start(); err != nil {
+			return err
+		}
+	}
+	if array {
+		_, err = hc.w.Write([]byte{']'})
+	}
+	return err
+}
+
+// XML send a XML response.
+func XML(c context.Context, code int, i interface{}) error {
+	b := getBuffer()
+	defer putBuffer(b)
+	b.WriteString(xml.Header)
+	if err := xml.NewEncoder(b).Encode(i); err != nil {
+		return err
+	}
+	return writeBuffer(c, code, "application/xml; charset=utf-8", b)
+}
+
+// Blob send a response of contentType.
+func Blob(c context.Context, code int, contentType string, b []byte) (err error) {
 	if c := fromCtx(c); c != nil {
-		c.w.Header().Add("Content-Type", "application/json")
+		c.w.Header().Set("Content-Type", contentType)
+		c.w.Header().Set("Content-Length", strconv.Itoa(len(b)))
 		c.w.WriteHeader(code)
-		var b []byte
-		b, err = json.Marshal(i)
-		if err != nil {
-			return
-		}
 		_, err = c.w.Write(b)
 		return
 	}
 	return ErrNotContext
 }
 
+func writeBuffer(c context.Context, code int, contentType string, b *bytes.Buffer) error {
+	return Blob(c, code, contentType, b.Bytes())
+}
+
+func getBuffer() *bytes.Buffer {
+	return bufPool.Get().(*bytes.Buffer)
+}
+
+func putBuffer(b *bytes.Buffer) {
+	// Large buffers are not reused to keep the pool small.
+	if b.Cap() > maxPooledBuffer {
+		return
+	}
+	b.Reset()
+	bufPool.Put(b)
+}
+
+// Stream send a response of contentType copied from r.
+func Stream(c context.Context, code int, contentType string, r io.Reader) (err error) {
+	if c := fromCtx(c); c != nil {
+		c.w.Header().Set("Content-Type", contentType)
+		c.w.WriteHeader(code)
+		_, err = io.Copy(c.w, r)
+		return
+	}
+	return ErrNotContext
+}
+
 // Redirect redirect the request status code.
 func Redirect(c context.Context, code int, url string) error {
 	if c := fromCtx(c); c != nil {
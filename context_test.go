@@ -1,10 +1,16 @@
 package horo
 
 import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/k2wanko/horo/log"
 	"golang.org/x/net/context"
 )
 
@@ -63,3 +69,253 @@ func TestRequestID(t *testing.T) {
 
 	h.ServeHTTP(w, r)
 }
+
+func TestTypedParams(t *testing.T) {
+	id := "f47ac10b-58cc-4372-a567-0e02b2c3d479"
+	h := New()
+	h.GET("/users/:id/:uuid", func(c context.Context) error {
+		n, err := ParamInt(c, "id")
+		if err != nil {
+			return err
+		}
+		n64, err := ParamInt64(c, "id")
+		if err != nil {
+			return err
+		}
+		u, err := ParamUUID(c, "uuid")
+		if err != nil {
+			return err
+		}
+		b, err := QueryBool(c, "active")
+		if err != nil {
+			return err
+		}
+		tm, err := QueryTime(c, "since", "2006-01-02")
+		if err != nil {
+			return err
+		}
+		return Text(c, 200, fmt.Sprintf("%d %d %s %v %s %s %s %d", n, n64, u, b, tm.Format("Jan 2"),
+			QueryParam(c, "q"), QueryDefault(c, "sort", "name"), len(QueryParams(c)["tag"])))
+	})
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/users/1/" + id + "?active=1&since=2016-01-02&q=go&tag=a&tag=b", 200, "1 1 " + id + " true Jan 2 go name 2"},
+		{"/users/1/" + id, 200, "1 1 " + id + " false Jan 1  name 0"},
+		{"/users/x/" + id, 400, `invalid path parameter "id"`},
+		{"/users/1/x", 400, `invalid path parameter "uuid"`},
+		{"/users/1/" + id + "?active=maybe", 400, `invalid query parameter "active"`},
+		{"/users/1/" + id + "?since=yesterday", 400, `invalid query parameter "since"`},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", tt.path, nil)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if code := w.Code; code != tt.code {
+			t.Errorf("%s: w.Code = %v; want %v", tt.path, code, tt.code)
+		}
+
+		if body := w.Body.String(); body != tt.body {
+			t.Errorf("%s: w.Body = %v; want %v", tt.path, body, tt.body)
+		}
+	}
+}
+
+func TestResponseHelpers(t *testing.T) {
+	type user struct {
+		Name string `json:"name" xml:"name"`
+	}
+
+	var size int64
+	h := New()
+	h.Debug = true
+	h.Use(func(next HandlerFunc) HandlerFunc {
+		return func(c context.Context) (err error) {
+			err = next(c)
+			size = Response(c).Size()
+			return
+		}
+	})
+	h.GET("/xml", func(c context.Context) error {
+		return XML(c, 200, user{"gopher"})
+	})
+	h.GET("/jsonp", func(c context.Context) error {
+		return JSONP(c, 200, QueryParam(c, "callback"), user{"gopher"})
+	})
+	h.GET("/json", func(c context.Context) error {
+		return JSON(c, 200, user{"gopher"})
+	})
+	h.GET("/blob", func(c context.Context) error {
+		return Blob(c, 200, "image/png", []byte{0x89, 'P', 'N', 'G'})
+	})
+	h.GET("/stream", func(c context.Context) error {
+		return Stream(c, 200, "text/csv; charset=utf-8", strings.NewReader("a,b\n1,2\n"))
+	})
+
+	tests := []struct {
+		path        string
+		code        int
+		contentType string
+		body        string
+	}{
+		{"/xml", 200, "application/xml; charset=utf-8", xml.Header + "<user><name>gopher</name></user>"},
+		{"/jsonp?callback=jQuery.cb_1", 200, "application/javascript; charset=utf-8", `/**/jQuery.cb_1({"name":"gopher"});`},
+		{"/jsonp?callback=alert(1)", 400, "text/plain", "invalid JSONP callback"},
+		{"/json", 200, "application/json; charset=utf-8", `{"name":"gopher"}`},
+		{"/json?pretty", 200, "application/json; charset=utf-8", "{\n  \"name\": \"gopher\"\n}"},
+		{"/blob", 200, "image/png", "\x89PNG"},
+		{"/stream", 200, "text/csv; charset=utf-8", "a,b\n1,2\n"},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", tt.path, nil)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if code := w.Code; code != tt.code {
+			t.Errorf("%s: w.Code = %v; want %v", tt.path, code, tt.code)
+		}
+
+		if ct := w.Header().Get("Content-Type"); ct != tt.contentType {
+			t.Errorf("%s: Content-Type = %v; want %v", tt.path, ct, tt.contentType)
+		}
+
+		if body := w.Body.String(); body != tt.body {
+			t.Errorf("%s: w.Body = %v; want %v", tt.path, body, tt.body)
+		}
+
+		if want := int64(len(tt.body)); tt.code == 200 && size != want {
+			t.Errorf("%s: Size() = %v; want %v", tt.path, size, want)
+		}
+	}
+
+	if err := Blob(context.Background(), 200, "text/plain", nil); err != ErrNotContext {
+		t.Errorf("err = %v; want %v", err, ErrNotContext)
+	}
+}
+
+func TestJSONMarshalError(t *testing.T) {
+	h := New()
+	h.GET("/", func(c context.Context) error {
+		return JSON(c, 200, map[string]interface{}{"ch": make(chan int)})
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if code, want := w.Code, 500; code != want {
+		t.Errorf("w.Code = %v; want %v", code, want)
+	}
+
+	if body, want := w.Body.String(), http.StatusText(500); body != want {
+		t.Errorf("w.Body = %v; want %v", body, want)
+	}
+}
+
+func TestJSONContentLength(t *testing.T) {
+	h := New()
+	h.GET("/", func(c context.Context) error {
+		return JSON(c, 200, map[string]string{"user": "k2wanko"})
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if cl, want := w.Header().Get("Content-Length"), "18"; cl != want {
+		t.Errorf("Content-Length = %v; want %v", cl, want)
+	}
+}
+
+func TestJSONStream(t *testing.T) {
+	iter := func(n int) JSONIter {
+		return func(yield func(v interface{}) error) error {
+			for i := 0; i < n; i++ {
+				if err := yield(map[string]int{"id": i}); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+
+	h := New()
+	h.GET("/array/:n", func(c context.Context) error {
+		n, err := ParamInt(c, "n")
+		if err != nil {
+			return err
+		}
+		return JSONStream(c, 200, iter(n))
+	})
+	h.GET("/ndjson", func(c context.Context) error {
+		return NDJSON(c, 200, iter(2))
+	})
+
+	tests := []struct {
+		path        string
+		contentType string
+		body        string
+	}{
+		{"/array/0", "application/json; charset=utf-8", "[]"},
+		{"/array/2", "application/json; charset=utf-8", "[{\"id\":0}\n,{\"id\":1}\n]"},
+		{"/ndjson", "application/x-ndjson", "{\"id\":0}\n{\"id\":1}\n"},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", tt.path, nil)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if ct := w.Header().Get("Content-Type"); ct != tt.contentType {
+			t.Errorf("%s: Content-Type = %v; want %v", tt.path, ct, tt.contentType)
+		}
+
+		if body := w.Body.String(); body != tt.body {
+			t.Errorf("%s: w.Body = %q; want %q", tt.path, body, tt.body)
+		}
+	}
+}
+
+func TestJSONStreamErrorBeforeFirstValue(t *testing.T) {
+	h := New()
+	h.Logger = log.New(log.ErrOut(io.Discard))
+	h.GET("/", func(c context.Context) error {
+		return JSONStream(c, 200, func(yield func(v interface{}) error) error {
+			return errors.New("Test")
+		})
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if code, want := w.Code, 500; code != want {
+		t.Errorf("w.Code = %v; want %v", code, want)
+	}
+
+	if body, want := w.Body.String(), http.StatusText(500); body != want {
+		t.Errorf("w.Body = %q; want %q", body, want)
+	}
+}
+
+func BenchmarkJSON(b *testing.B) {
+	h := New()
+	h.GET("/", func(c context.Context) error {
+		return JSON(c, 200, map[string]string{"user": "k2wanko"})
+	})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	benchRequest(b, h, req)
+}
@@ -0,0 +1,130 @@
+package horo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/k2wanko/horo/log"
+	"golang.org/x/net/context"
+)
+
+type (
+	errorPage struct {
+		prefix string
+		lo, hi int
+		hf     HandlerFunc
+	}
+)
+
+// ErrorPage registers hf rendering error responses of status.
+// status is a code such as "404", or a range such as "5xx".
+// The page gets the status and the error by PageError.
+// The response is written with the error status whatever status the page
+// writes, so a page can not turn an error into a success.
+func (h *Horo) ErrorPage(status string, hf HandlerFunc) {
+	h.addErrorPage("", status, hf)
+}
+
+// ErrorPage registers hf rendering error responses of status under the
+// group prefix. It overrides pages registered for shorter prefixes.
+func (g *Group) ErrorPage(status string, hf HandlerFunc) {
+	g.h.addErrorPage(strings.TrimSuffix(g.prefix, "/"), status, hf)
+}
+
+func (h *Horo) addErrorPage(prefix, status string, hf HandlerFunc) {
+	lo, hi, err := parseStatus(status)
+	if err != nil {
+		panic(err)
+	}
+	h.errorPages = append(h.errorPages, &errorPage{prefix, lo, hi, hf})
+}
+
+func parseStatus(s string) (lo, hi int, err error) {
+	if len(s) == 3 && strings.HasSuffix(s, "xx") && s[0] >= '1' && s[0] <= '5' {
+		lo = int(s[0]-'0') * 100
+		return lo, lo + 99, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 100 || n > 599 {
+		return 0, 0, fmt.Errorf("horo: invalid error page status %q", s)
+	}
+	return n, n, nil
+}
+
+// PageError returns the status code and error rendered by an error page.
+func PageError(c context.Context) (code int, err error) {
+	if hc := fromCtx(c); hc != nil {
+		code, err = hc.pageCode, hc.pageErr
+	}
+	return
+}
+
+// lookupErrorPage returns the page of the longest prefix matching path.
+// A code is preferred to a range of the same prefix.
+func (h *Horo) lookupErrorPage(path string, code int) *errorPage {
+	var best *errorPage
+	for _, p := range h.errorPages {
+		if code < p.lo || code > p.hi {
+			continue
+		}
+		if p.prefix != "" && path != p.prefix && !strings.HasPrefix(path, p.prefix+"/") {
+			continue
+		}
+		if best == nil || len(p.prefix) > len(best.prefix) ||
+			len(p.prefix) == len(best.prefix) && p.hi-p.lo < best.hi-best.lo {
+			best = p
+		}
+	}
+	return best
+}
+
+// renderErrorPage renders the error page of err if registered.
+// It reports whether the response is written. A page is tried once per request.
+func (h *Horo) renderErrorPage(c context.Context, err error) bool {
+	hc := fromCtx(c)
+	if hc == nil || hc.w.Committed() || hc.pageErr != nil || len(h.errorPages) == 0 {
+		return false
+	}
+
+	code := errorStatus(err)
+	p := h.lookupErrorPage(hc.r.URL.Path, code)
+	if p == nil {
+		return false
+	}
+
+	hc.pageCode, hc.pageErr = code, err
+	hc.w.forceCode = code
+	defer func() {
+		hc.w.forceCode = 0
+	}()
+	if perr := p.hf(c); perr != nil {
+		log.FromContext(c).Errorf(c, "error page %d: %v", code, perr)
+		return hc.w.Committed()
+	}
+	return true
+}
+
+// withErrorPage renders the error page of err, or returns err.
+// NotFound and MethodNotAllowed use pages with any ErrorHandler.
+func (h *Horo) withErrorPage(c context.Context, err error) error {
+	if err != nil && h.renderErrorPage(c, err) {
+		return nil
+	}
+	return err
+}
+
+// errorStatus returns the response status of err.
+func errorStatus(err error) int {
+	var he *HTTPError
+	var ve *ValidationError
+	switch {
+	case errors.As(err, &he):
+		return he.Code
+	case errors.As(err, &ve):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,124 @@
+package horo
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestErrorPage(t *testing.T) {
+	page := func(name string) HandlerFunc {
+		return func(c context.Context) error {
+			code, err := PageError(c)
+			return HTML(c, code, fmt.Sprintf("<h1>%s %d: %v</h1>", name, code, err))
+		}
+	}
+
+	h := New()
+	h.ErrorPage("404", page("site"))
+	h.ErrorPage("5xx", page("site"))
+	h.ErrorPage("503", page("maintenance"))
+	h.GET("/boom", func(c context.Context) error {
+		return errors.New("boom")
+	})
+	h.GET("/down", func(c context.Context) error {
+		return NewHTTPError(http.StatusServiceUnavailable, "")
+	})
+	h.GET("/bad", func(c context.Context) error {
+		return NewHTTPError(http.StatusBadRequest, "bad")
+	})
+
+	api := h.Group("/api")
+	api.ErrorPage("404", func(c context.Context) error {
+		return JSON(c, http.StatusNotFound, map[string]string{"error": "not found"})
+	})
+	api.GET("/boom", func(c context.Context) error {
+		return errors.New("boom")
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{"GET", "/missing", 404, "<h1>site 404: Not Found</h1>"},
+		{"GET", "/boom", 500, "<h1>site 500: boom</h1>"},
+		{"GET", "/down", 503, "<h1>maintenance 503: Service Unavailable</h1>"},
+		{"GET", "/bad", 400, "bad"},
+		{"POST", "/boom", 405, "Method Not Allowed"},
+		{"GET", "/api/missing", 404, `{"error":"not found"}`},
+		{"GET", "/api/boom", 500, "<h1>site 500: boom</h1>"},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest(tt.method, tt.path, nil)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if code := w.Code; code != tt.code {
+			t.Errorf("%s %s: w.Code = %v; want %v", tt.method, tt.path, code, tt.code)
+		}
+
+		if body := w.Body.String(); body != tt.body {
+			t.Errorf("%s %s: w.Body = %v; want %v", tt.method, tt.path, body, tt.body)
+		}
+	}
+}
+
+func TestErrorPageCustomErrorHandler(t *testing.T) {
+	h := New()
+	h.ErrorHandler = func(c context.Context, err error) {
+		Text(c, 599, "custom")
+	}
+	h.ErrorPage("404", func(c context.Context) error {
+		return Text(c, http.StatusNotFound, "page")
+	})
+
+	r, _ := http.NewRequest("GET", "/missing", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if body, want := w.Body.String(), "page"; body != want {
+		t.Errorf("w.Body = %v; want %v", body, want)
+	}
+}
+
+func TestErrorPageInvalidStatus(t *testing.T) {
+	for _, s := range []string{"", "6xx", "abc", "99"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("ErrorPage(%q) is not panicked", s)
+				}
+			}()
+			New().ErrorPage(s, NotFound)
+		}()
+	}
+}
+
+func TestErrorPageStatus(t *testing.T) {
+	h := New()
+	h.ErrorPage("404", func(c context.Context) error {
+		return HTML(c, http.StatusOK, "<h1>not found</h1>")
+	})
+
+	r, _ := http.NewRequest("GET", "/missing", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if code, want := w.Code, 404; code != want {
+		t.Errorf("w.Code = %v; want %v", code, want)
+	}
+
+	if body, want := w.Body.String(), "<h1>not found</h1>"; body != want {
+		t.Errorf("w.Body = %v; want %v", body, want)
+	}
+}
@@ -0,0 +1,110 @@
+package horo
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// File send the file content. It supports Range and conditional requests.
+func File(c context.Context, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fsError(c, err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return fsError(c, os.ErrNotExist)
+	}
+	return content(c, f, fi.Name(), fi.ModTime())
+}
+
+// Attachment send r as a download named name.
+func Attachment(c context.Context, r io.ReadSeeker, name string, modtime time.Time) error {
+	return disposition(c, "attachment", r, name, modtime)
+}
+
+// Inline send r to be displayed in the browser as name.
+func Inline(c context.Context, r io.ReadSeeker, name string, modtime time.Time) error {
+	return disposition(c, "inline", r, name, modtime)
+}
+
+func disposition(c context.Context, typ string, r io.ReadSeeker, name string, modtime time.Time) error {
+	w := Response(c)
+	if w == nil {
+		return ErrNotContext
+	}
+	w.Header().Set("Content-Disposition", contentDisposition(typ, filepath.Base(name)))
+	return content(c, r, name, modtime)
+}
+
+func content(c context.Context, r io.ReadSeeker, name string, modtime time.Time) error {
+	if c := fromCtx(c); c != nil {
+		http.ServeContent(c.w, c.r, name, modtime, r)
+		return nil
+	}
+	return ErrNotContext
+}
+
+// contentDisposition returns Content-Disposition value with RFC 5987 filename.
+func contentDisposition(typ, name string) string {
+	ascii := true
+	fallback := make([]byte, 0, len(name))
+	for i := 0; i < len(name); i++ {
+		b := name[i]
+		switch {
+		case b >= 0x80:
+			ascii = false
+			// Replace each non-ASCII rune with one '_'.
+			if b >= 0xC0 {
+				fallback = append(fallback, '_')
+			}
+		case b < 0x20 || b == 0x7F:
+			fallback = append(fallback, '_')
+		case b == '"' || b == '\\':
+			fallback = append(fallback, '\\', b)
+		default:
+			fallback = append(fallback, b)
+		}
+	}
+
+	v := typ + `; filename="` + string(fallback) + `"`
+	if !ascii {
+		v += "; filename*=UTF-8''" + rfc5987Escape(name)
+	}
+	return v
+}
+
+func rfc5987Escape(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isAttrChar(c) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0xF])
+	}
+	return b.String()
+}
+
+func isAttrChar(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+}
@@ -0,0 +1,120 @@
+package horo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "report.txt")
+	if err := os.WriteFile(p, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fi, _ := os.Stat(p)
+	lm := fi.ModTime().UTC().Format(http.TimeFormat)
+
+	var status int
+	h := New()
+	h.Use(func(next HandlerFunc) HandlerFunc {
+		return func(c context.Context) (err error) {
+			err = next(c)
+			status = Response(c).Status()
+			return
+		}
+	})
+	h.GET("/file", func(c context.Context) error {
+		return File(c, p)
+	})
+	h.GET("/missing", func(c context.Context) error {
+		return File(c, filepath.Join(dir, "missing.txt"))
+	})
+
+	tests := []struct {
+		path    string
+		header  map[string]string
+		code    int
+		body    string
+		ctRange string
+	}{
+		{"/file", nil, 200, "0123456789", ""},
+		{"/file", map[string]string{"Range": "bytes=2-4"}, 206, "234", "bytes 2-4/10"},
+		{"/file", map[string]string{"Range": "bytes=20-30"}, 416, "", "bytes */10"},
+		{"/file", map[string]string{"Range": "bytes=2-4", "If-Range": lm}, 206, "234", "bytes 2-4/10"},
+		{"/file", map[string]string{"Range": "bytes=2-4", "If-Range": `"other"`}, 200, "0123456789", ""},
+		{"/missing", nil, 404, "Not Found", ""},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", tt.path, nil)
+		for k, v := range tt.header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if code := w.Code; code != tt.code {
+			t.Errorf("%s %v: w.Code = %v; want %v", tt.path, tt.header, code, tt.code)
+		}
+
+		if body := w.Body.String(); tt.code != 416 && body != tt.body {
+			t.Errorf("%s %v: w.Body = %q; want %q", tt.path, tt.header, body, tt.body)
+		}
+
+		if cr := w.Header().Get("Content-Range"); cr != tt.ctRange {
+			t.Errorf("%s %v: Content-Range = %v; want %v", tt.path, tt.header, cr, tt.ctRange)
+		}
+
+		if tt.code != 404 && status != tt.code {
+			t.Errorf("%s %v: Status() = %v; want %v", tt.path, tt.header, status, tt.code)
+		}
+	}
+}
+
+func TestAttachment(t *testing.T) {
+	modtime := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+	h := New()
+	h.GET("/attachment/:name", func(c context.Context) error {
+		return Attachment(c, strings.NewReader("a,b\n"), Param(c, "name"), modtime)
+	})
+	h.GET("/inline/:name", func(c context.Context) error {
+		return Inline(c, strings.NewReader("%PDF"), Param(c, "name"), modtime)
+	})
+
+	tests := []struct {
+		path        string
+		disposition string
+		contentType string
+	}{
+		{"/attachment/report.csv", `attachment; filename="report.csv"`, "text/csv; charset=utf-8"},
+		{"/attachment/" + "%E5%A0%B1%E5%91%8A%20%22q%22.csv", `attachment; filename="__ \"q\".csv"; filename*=UTF-8''%E5%A0%B1%E5%91%8A%20%22q%22.csv`, "text/csv; charset=utf-8"},
+		{"/inline/doc.pdf", `inline; filename="doc.pdf"`, "application/pdf"},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", tt.path, nil)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if cd := w.Header().Get("Content-Disposition"); cd != tt.disposition {
+			t.Errorf("%s: Content-Disposition = %v; want %v", tt.path, cd, tt.disposition)
+		}
+
+		if ct := w.Header().Get("Content-Type"); ct != tt.contentType {
+			t.Errorf("%s: Content-Type = %v; want %v", tt.path, ct, tt.contentType)
+		}
+
+		if lm, want := w.Header().Get("Last-Modified"), modtime.Format(http.TimeFormat); lm != want {
+			t.Errorf("%s: Last-Modified = %v; want %v", tt.path, lm, want)
+		}
+	}
+}
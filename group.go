@@ -0,0 +1,110 @@
+package horo
+
+import "net/http"
+
+type (
+	// Group is a set of routes that share a path prefix and middleware.
+	Group struct {
+		h          *Horo
+		parent     *Group
+		prefix     string
+		middleware []MiddlewareFunc
+	}
+)
+
+// Group creates a new route group with prefix and group-scoped middleware.
+func (h *Horo) Group(prefix string, mw ...MiddlewareFunc) *Group {
+	return &Group{
+		h:          h,
+		prefix:     prefix,
+		middleware: append([]MiddlewareFunc(nil), mw...),
+	}
+}
+
+// Group creates a nested group. It inherits the parent prefix and middleware.
+func (g *Group) Group(prefix string, mw ...MiddlewareFunc) *Group {
+	return &Group{
+		h:          g.h,
+		parent:     g,
+		prefix:     g.prefix + prefix,
+		middleware: append([]MiddlewareFunc(nil), mw...),
+	}
+}
+
+// Use is add group middleware.
+// Middleware chains of registered group routes are rebuilt.
+func (g *Group) Use(mw ...MiddlewareFunc) {
+	g.middleware = append(g.middleware, mw...)
+	g.h.rebuild()
+}
+
+// GET registers a new GET handler
+func (g *Group) GET(path string, hf HandlerFunc, mw ...MiddlewareFunc) *Route {
+	return g.Handle("GET", path, hf, mw...)
+}
+
+// POST registers a new POST handler
+func (g *Group) POST(path string, hf HandlerFunc, mw ...MiddlewareFunc) *Route {
+	return g.Handle("POST", path, hf, mw...)
+}
+
+// PATCH registers a new PATCH handler
+func (g *Group) PATCH(path string, hf HandlerFunc, mw ...MiddlewareFunc) *Route {
+	return g.Handle("PATCH", path, hf, mw...)
+}
+
+// PUT registers a new PUT handler
+func (g *Group) PUT(path string, hf HandlerFunc, mw ...MiddlewareFunc) *Route {
+	return g.Handle("PUT", path, hf, mw...)
+}
+
+// OPTIONS registers a new OPTIONS handler
+func (g *Group) OPTIONS(path string, hf HandlerFunc, mw ...MiddlewareFunc) *Route {
+	return g.Handle("OPTIONS", path, hf, mw...)
+}
+
+// DELETE registers a new DELETE handler
+func (g *Group) DELETE(path string, hf HandlerFunc, mw ...MiddlewareFunc) *Route {
+	return g.Handle("DELETE", path, hf, mw...)
+}
+
+// HEAD registers a new HEAD handler
+func (g *Group) HEAD(path string, hf HandlerFunc, mw ...MiddlewareFunc) *Route {
+	return g.Handle("HEAD", path, hf, mw...)
+}
+
+// Any registers a new handler for all standard HTTP methods
+func (g *Group) Any(path string, hf HandlerFunc, mw ...MiddlewareFunc) []*Route {
+	return g.Match(methods, path, hf, mw...)
+}
+
+// Match registers a new handler for the methods
+func (g *Group) Match(methods []string, path string, hf HandlerFunc, mw ...MiddlewareFunc) []*Route {
+	rts := make([]*Route, len(methods))
+	for i, m := range methods {
+		rts[i] = g.Handle(m, path, hf, mw...)
+	}
+	return rts
+}
+
+// Handle registers a new handler for the method
+func (g *Group) Handle(method, path string, hf HandlerFunc, mw ...MiddlewareFunc) *Route {
+	return g.h.add(g, method, g.prefix+path, hf, mw...)
+}
+
+// Mount registers a http.Handler for all paths under the prefix.
+func (g *Group) Mount(prefix string, hh http.Handler, mw ...MiddlewareFunc) {
+	g.h.mount(g, g.prefix+prefix, hh, mw...)
+}
+
+// stack returns the middleware of the parent groups followed by the group.
+func (g *Group) stack() []MiddlewareFunc {
+	if g == nil {
+		return nil
+	}
+	if g.parent == nil {
+		return g.middleware
+	}
+	m := g.parent.stack()
+	return append(m[:len(m):len(m)], g.middleware...)
+}
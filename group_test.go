@@ -0,0 +1,120 @@
+package horo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kylelemons/godebug/pretty"
+	"golang.org/x/net/context"
+)
+
+func TestGroup(t *testing.T) {
+	var res []string
+	mw := func(name string) MiddlewareFunc {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c context.Context) error {
+				res = append(res, name)
+				return next(c)
+			}
+		}
+	}
+
+	h := New()
+	h.Use(mw("global"))
+
+	api := h.Group("/api", mw("api"))
+	admin := api.Group("/v1/admin")
+	admin.Use(mw("admin"))
+	admin.GET("/users/:id", func(c context.Context) error {
+		res = append(res, "handler")
+		return Text(c, 200, Param(c, "id"))
+	}, mw("route"))
+
+	r, _ := http.NewRequest("GET", "/api/v1/admin/users/me", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if code, want := w.Code, 200; code != want {
+		t.Errorf("w.Code = %v; want %v", code, want)
+	}
+
+	if body, want := w.Body.String(), "me"; body != want {
+		t.Errorf("w.Body = %v; want %v", body, want)
+	}
+
+	if diff := pretty.Compare(res, []string{"global", "api", "admin", "route", "handler"}); diff != "" {
+		t.Errorf("diff:\n%s", diff)
+	}
+}
+
+func TestGroupMiddlewareScope(t *testing.T) {
+	var called bool
+	h := New()
+	g := h.Group("/admin", func(next HandlerFunc) HandlerFunc {
+		return func(c context.Context) error {
+			called = true
+			return next(c)
+		}
+	})
+	g.GET("/", func(c context.Context) error {
+		return NoContent(c, 204)
+	})
+	h.GET("/", func(c context.Context) error {
+		return NoContent(c, 204)
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if called {
+		t.Errorf("group middleware called outside the group")
+	}
+
+	r, _ = http.NewRequest("GET", "/admin/", nil)
+	w = httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if !called {
+		t.Errorf("group middleware not called")
+	}
+}
+
+func TestGroupUseAfterRegistration(t *testing.T) {
+	var res []string
+	mw := func(name string) MiddlewareFunc {
+		return func(next HandlerFunc) HandlerFunc {
+			return func(c context.Context) error {
+				res = append(res, name)
+				return next(c)
+			}
+		}
+	}
+
+	h := New()
+	admin := h.Group("/admin")
+	users := admin.Group("/users")
+	users.GET("/:id", func(c context.Context) error {
+		res = append(res, "handler")
+		return NoContent(c, 204)
+	}, mw("route"))
+	users.Use(mw("users"))
+	admin.Use(mw("auth"))
+
+	r, _ := http.NewRequest("GET", "/admin/users/me", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if diff := pretty.Compare(res, []string{"auth", "users", "route", "handler"}); diff != "" {
+		t.Errorf("diff:\n%s", diff)
+	}
+
+	if n, want := h.Routes()[0].Middleware, 3; n != want {
+		t.Errorf("Middleware = %v; want %v", n, want)
+	}
+}
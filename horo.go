@@ -55,7 +55,10 @@ package horo
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"runtime/debug"
+	"strings"
 	"sync"
 
 	"github.com/julienschmidt/httprouter"
@@ -70,10 +73,35 @@ type (
 		NotFound, MethodNotAllowed HandlerFunc
 		Logger                     log.Logger
 		RequestIDGenerator         RequestIDGenerator
+		Validator                  Validator
+		Renderer                   Renderer
+
+		// Debug enables debug features such as ?pretty JSON responses.
+		Debug bool
+
+		// ProblemDetails makes DefaultErrorHandler write RFC 7807 responses.
+		ProblemDetails bool
+
+		// AbortOnCommittedError aborts the connection if a handler returns an
+		// error after the response is committed, so clients of streaming
+		// responses see a truncated transfer.
+		AbortOnCommittedError bool
+
+		// PanicHandler is called with the recovered value and stack of a
+		// panic in a handler, before ErrorHandler.
+		PanicHandler func(c context.Context, v interface{}, stack []byte)
 
 		router     *httprouter.Router
 		middleware []MiddlewareFunc
+		routes     []*Route
+		names      map[string]*Route
+		fallbacks  []*fallback
+		mounted    *Route
+		encoders   []encoder
+		errorPages []*errorPage
 		pool       sync.Pool
+
+		notFound, methodNotAllowed *Route
 	}
 
 	// HandlerFunc is server HTTP requests.
@@ -85,10 +113,28 @@ type (
 	// ErrorHandlerFunc is error handling function.
 	ErrorHandlerFunc func(context.Context, error)
 
+	// PanicError is a recovered panic passed to ErrorHandler as the
+	// internal cause of a 500 *HTTPError.
+	PanicError struct {
+		Value interface{}
+		Stack []byte
+	}
+
 	// HTTPError handling a request.
+	//
+	// Type, Title, Detail, Instance and Extensions are RFC 7807 members
+	// written when Horo.ProblemDetails is set.
+	// Internal is the cause logged by DefaultErrorHandler, never sent to clients.
 	HTTPError struct {
-		Code    int
-		Message string
+		Code     int
+		Message  string
+		Internal error
+
+		Type       string
+		Title      string
+		Detail     string
+		Instance   string
+		Extensions map[string]interface{}
 	}
 )
 
@@ -98,6 +144,32 @@ var (
 
 	// ErrInvalidRedirectCode is thrown if invalid redirect code.
 	ErrInvalidRedirectCode = errors.New("invalid redirect status code")
+
+	// ErrNotHijacker is thrown if the ResponseWriter does not support hijacking.
+	ErrNotHijacker = errors.New("http.Hijacker is not supported")
+
+	methods = []string{
+		http.MethodGet,
+		http.MethodHead,
+		http.MethodPost,
+		http.MethodPut,
+		http.MethodPatch,
+		http.MethodDelete,
+		http.MethodConnect,
+		http.MethodOptions,
+		http.MethodTrace,
+	}
+
+	// mountMethods are the methods passed on to mounted handlers.
+	mountMethods = []string{
+		http.MethodGet,
+		http.MethodHead,
+		http.MethodPost,
+		http.MethodPut,
+		http.MethodPatch,
+		http.MethodDelete,
+		http.MethodOptions,
+	}
 )
 
 // New is create Horo instance.
@@ -107,7 +179,10 @@ func New() (h *Horo) {
 		NotFound:         NotFound,
 		MethodNotAllowed: MethodNotAllowed,
 		Logger:           log.DefaultLogger,
+		Validator:        DefaultValidator,
 		router:           httprouter.New(),
+		names:            map[string]*Route{},
+		encoders:         defaultEncoders(),
 	}
 
 	h.pool.New = func() interface{} {
@@ -118,6 +193,13 @@ func New() (h *Horo) {
 		}
 	}
 
+	h.notFound = h.newRoute(nil, func(c context.Context) error {
+		return h.withErrorPage(c, h.handleFallback(c))
+	})
+	h.methodNotAllowed = h.newRoute(nil, func(c context.Context) error {
+		return h.withErrorPage(c, h.MethodNotAllowed(c))
+	})
+
 	h.router.NotFound = http.HandlerFunc(h.handleNotFound)
 	h.router.MethodNotAllowed = http.HandlerFunc(h.handleMethodNotAllowed)
 
@@ -125,104 +207,255 @@ func New() (h *Horo) {
 }
 
 // Use is add middleware.
+// Middleware chains of registered routes are rebuilt.
 func (h *Horo) Use(mw ...MiddlewareFunc) {
 	h.middleware = append(h.middleware, mw...)
+	h.rebuild()
+}
+
+// rebuild rebuilds middleware chains of registered routes.
+func (h *Horo) rebuild() {
+	h.notFound.build(h.middleware)
+	h.methodNotAllowed.build(h.middleware)
+	for _, rt := range h.routes {
+		rt.build(h.middleware)
+	}
+	// Global middleware is applied by the NotFound chain.
+	for _, fb := range h.fallbacks {
+		fb.rt.build(nil)
+	}
+	if h.mounted != nil {
+		h.mounted.build(nil)
+	}
 }
 
 // GET registers a new GET handler
-func (h *Horo) GET(path string, hf HandlerFunc, mw ...MiddlewareFunc) {
-	h.router.GET(path, h.handle(hf, mw...))
+func (h *Horo) GET(path string, hf HandlerFunc, mw ...MiddlewareFunc) *Route {
+	return h.Handle("GET", path, hf, mw...)
 }
 
 // POST registers a new POST handler
-func (h *Horo) POST(path string, hf HandlerFunc, mw ...MiddlewareFunc) {
-	h.router.POST(path, h.handle(hf, mw...))
+func (h *Horo) POST(path string, hf HandlerFunc, mw ...MiddlewareFunc) *Route {
+	return h.Handle("POST", path, hf, mw...)
 }
 
 // PATCH registers a new PATCH handler
-func (h *Horo) PATCH(path string, hf HandlerFunc, mw ...MiddlewareFunc) {
-	h.router.PATCH(path, h.handle(hf, mw...))
+func (h *Horo) PATCH(path string, hf HandlerFunc, mw ...MiddlewareFunc) *Route {
+	return h.Handle("PATCH", path, hf, mw...)
 }
 
 // PUT registers a new PUT handler
-func (h *Horo) PUT(path string, hf HandlerFunc, mw ...MiddlewareFunc) {
-	h.router.PUT(path, h.handle(hf, mw...))
+func (h *Horo) PUT(path string, hf HandlerFunc, mw ...MiddlewareFunc) *Route {
+	return h.Handle("PUT", path, hf, mw...)
 }
 
 // OPTIONS registers a new OPTIONS handler
-func (h *Horo) OPTIONS(path string, hf HandlerFunc, mw ...MiddlewareFunc) {
-	h.router.OPTIONS(path, h.handle(hf, mw...))
+func (h *Horo) OPTIONS(path string, hf HandlerFunc, mw ...MiddlewareFunc) *Route {
+	return h.Handle("OPTIONS", path, hf, mw...)
 }
 
 // DELETE registers a new DELETE handler
-func (h *Horo) DELETE(path string, hf HandlerFunc, mw ...MiddlewareFunc) {
-	h.router.DELETE(path, h.handle(hf, mw...))
+func (h *Horo) DELETE(path string, hf HandlerFunc, mw ...MiddlewareFunc) *Route {
+	return h.Handle("DELETE", path, hf, mw...)
 }
 
 // HEAD registers a new HEAD handler
-func (h *Horo) HEAD(path string, hf HandlerFunc, mw ...MiddlewareFunc) {
-	h.router.HEAD(path, h.handle(hf, mw...))
+func (h *Horo) HEAD(path string, hf HandlerFunc, mw ...MiddlewareFunc) *Route {
+	return h.Handle("HEAD", path, hf, mw...)
+}
+
+// Any registers a new handler for all standard HTTP methods
+func (h *Horo) Any(path string, hf HandlerFunc, mw ...MiddlewareFunc) []*Route {
+	return h.Match(methods, path, hf, mw...)
+}
+
+// Match registers a new handler for the methods
+func (h *Horo) Match(methods []string, path string, hf HandlerFunc, mw ...MiddlewareFunc) []*Route {
+	rts := make([]*Route, len(methods))
+	for i, m := range methods {
+		rts[i] = h.Handle(m, path, hf, mw...)
+	}
+	return rts
+}
+
+// Handle registers a new handler for the method
+//
+// A param may have a constraint such as :id{int}, :id{uuid} or
+// :slug{[a-z-]+}. The request is handled as not found if it does not match.
+func (h *Horo) Handle(method, path string, hf HandlerFunc, mw ...MiddlewareFunc) *Route {
+	return h.add(nil, method, path, hf, mw...)
+}
+
+func (h *Horo) add(g *Group, method, path string, hf HandlerFunc, mw ...MiddlewareFunc) *Route {
+	rt := h.newRoute(g, hf, mw...)
+	rt.method = method
+	rt.path, rt.constraints = parseConstraints(path)
+	h.router.Handle(method, rt.path, h.handle(rt))
+	h.routes = append(h.routes, rt)
+	return rt
+}
+
+// Mount registers a http.Handler for all paths under the prefix.
+// The handler sees the request path with the prefix stripped.
+// CONNECT and TRACE requests are not passed on.
+//
+// A handler mounted at the root serves requests no route matches, through
+// the NotFound middleware chain. A path registered for another method is
+// still handled by MethodNotAllowed. It panics if the root is already mounted.
+func (h *Horo) Mount(prefix string, hh http.Handler, mw ...MiddlewareFunc) {
+	h.mount(nil, prefix, hh, mw...)
+}
+
+func (h *Horo) mount(g *Group, prefix string, hh http.Handler, mw ...MiddlewareFunc) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		// A catch-all at the root conflicts with every other route.
+		if h.mounted != nil {
+			panic("horo: a handler is already mounted at the root")
+		}
+		h.mounted = &Route{h: h, group: g, method: "*", path: "/", hf: WrapHandler(hh), mw: mw}
+		h.mounted.build(nil)
+		return
+	}
+
+	hf := WrapHandler(http.StripPrefix(prefix, hh))
+	for _, m := range mountMethods {
+		h.add(g, m, prefix+"/*"+mountParam, hf, mw...)
+	}
 }
 
-func (h *Horo) handle(hf HandlerFunc, mwf ...MiddlewareFunc) httprouter.Handle {
+func (h *Horo) handle(rt *Route) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-		h.serve(w, r, ps, hf, mwf...)
+		if !rt.match(ps) {
+			h.handleNotFound(w, r)
+			return
+		}
+		h.serve(w, r, ps, rt.chain)
 	}
 }
 
 func (h *Horo) handleNotFound(w http.ResponseWriter, r *http.Request) {
-	h.serve(w, r, nil, h.NotFound)
+	h.serve(w, r, nil, h.notFound.chain)
 }
 
 func (h *Horo) handleMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
-	h.serve(w, r, nil, h.MethodNotAllowed)
+	h.serve(w, r, nil, h.methodNotAllowed.chain)
 }
 
-func (h *Horo) serve(w http.ResponseWriter, r *http.Request, ps httprouter.Params, hf HandlerFunc, mwf ...MiddlewareFunc) {
+func (h *Horo) serve(w http.ResponseWriter, r *http.Request, ps httprouter.Params, hf HandlerFunc) {
 	hc := h.pool.Get().(*horoCtx)
 	hc.Reset(w, r, ps)
 
 	c, cancel := context.WithCancel(hc)
 	c = log.WithContext(c, h.Logger)
 
-	hwl := len(h.middleware)
-	mw := make([]MiddlewareFunc, hwl+len(mwf))
-	for i := 0; i < cap(mw); i++ {
-		if i < hwl {
-			mw[i] = h.middleware[i]
-		} else {
-			mw[i] = mwf[i-hwl]
-		}
-	}
+	defer func() {
+		cancel()
+		hc.finish()
+		h.pool.Put(hc)
+	}()
 
-	f := func(c context.Context) error {
-		for i := len(mw) - 1; i >= 0; i-- {
-			hf = mw[i](hf)
+	if err := h.call(c, hf); err != nil {
+		if hc.w.Committed() {
+			h.committedError(c, hc.w, err)
+			return
 		}
-
-		return hf(c)
+		h.ErrorHandler(c, err)
 	}
+}
 
-	if err := f(c); err != nil {
-		h.ErrorHandler(c, err)
+// committedError logs err which can not be written to the committed response.
+func (h *Horo) committedError(c context.Context, w *ResponseWriter, err error) {
+	log.FromContext(c).Errorf(c, "%v: response already committed with status %d and %d bytes", err, w.Status(), w.Size())
+	if h.AbortOnCommittedError {
+		panic(http.ErrAbortHandler)
 	}
+}
 
-	cancel()
+// call calls hf and recovers a panic as *HTTPError.
+// http.ErrAbortHandler is panicked again to abort the response.
+func (h *Horo) call(c context.Context, hf HandlerFunc) (err error) {
+	defer func() {
+		v := recover()
+		if v == nil {
+			return
+		}
+		if v == http.ErrAbortHandler {
+			panic(v)
+		}
 
-	h.pool.Put(hc)
+		stack := debug.Stack()
+		log.FromContext(c).Errorf(c, "panic: %v\n%s", v, stack)
+		if h.PanicHandler != nil {
+			h.PanicHandler(c, v, stack)
+		}
+		err = NewHTTPError(http.StatusInternalServerError, "").WithInternal(&PanicError{Value: v, Stack: stack})
+	}()
+	return hf(c)
 }
 
 // DefaultErrorHandler invoke HTTP Error Handler
 func DefaultErrorHandler(c context.Context, err error) {
+	logError(c, err)
+
+	hc := fromCtx(c)
+	if hc != nil && hc.h.renderErrorPage(c, err) {
+		return
+	}
+
+	if hc != nil && hc.h.ProblemDetails {
+		writeProblem(c, err)
+		return
+	}
+
 	code := 500
 	msg := http.StatusText(code)
-	if he, ok := err.(*HTTPError); ok {
+	var he *HTTPError
+	var ve *ValidationError
+	switch {
+	case errors.As(err, &he):
 		code = he.Code
-		msg = he.Message
+		msg = he.Error()
+	case errors.As(err, &ve):
+		code = http.StatusUnprocessableEntity
+		msg = ve.Error()
+		if hc != nil {
+			hc.w.Header().Add("Vary", "Accept")
+		}
+		// Plain text is preferred if Accept is missing or a wildcard.
+		offers := []string{MIMETextPlain, MIMEApplicationJSON}
+		if r := Request(c); r != nil && negotiate(r.Header.Get("Accept"), offers) == MIMEApplicationJSON {
+			JSON(c, code, map[string]interface{}{
+				"message": http.StatusText(code),
+				"fields":  ve.Fields,
+			})
+			return
+		}
 	}
 	Text(c, code, msg)
 }
 
+// logError logs the cause of err not sent to clients.
+func logError(c context.Context, err error) {
+	var pe *PanicError
+	if errors.As(err, &pe) {
+		// The stack is logged on recovering.
+		return
+	}
+	var he *HTTPError
+	if errors.As(err, &he) {
+		if he.Internal != nil {
+			log.FromContext(c).Errorf(c, "%d %s: %v", he.Code, he.Error(), he.Internal)
+		}
+		return
+	}
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		return
+	}
+	log.FromContext(c).Errorf(c, "%v", err)
+}
+
 // NotFound is default not found handler.
 func NotFound(c context.Context) error {
 	return &HTTPError{
@@ -249,6 +482,38 @@ func (h *Horo) ListenAndServe(addr string) error {
 	return http.ListenAndServe(addr, h)
 }
 
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// NewHTTPError returns HTTPError. msg is the status text if empty.
+func NewHTTPError(code int, msg string) *HTTPError {
+	if msg == "" {
+		msg = http.StatusText(code)
+	}
+	return &HTTPError{Code: code, Message: msg}
+}
+
+// WithInternal returns a copy of e with the internal cause.
+func (e *HTTPError) WithInternal(err error) *HTTPError {
+	he := *e
+	he.Internal = err
+	return &he
+}
+
+// Unwrap returns the internal cause.
+func (e *HTTPError) Unwrap() error {
+	return e.Internal
+}
+
 func (e *HTTPError) Error() string {
-	return e.Message
+	switch {
+	case e.Message != "":
+		return e.Message
+	case e.Detail != "":
+		return e.Detail
+	case e.Title != "":
+		return e.Title
+	}
+	return http.StatusText(e.Code)
 }
@@ -1,12 +1,16 @@
 package horo
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/k2wanko/horo/log"
 	"github.com/kylelemons/godebug/pretty"
 	"golang.org/x/net/context"
 )
@@ -87,6 +91,174 @@ func TestHTTPError(t *testing.T) {
 	}
 }
 
+func TestHTTPErrorDetail(t *testing.T) {
+	h := New()
+	h.GET("/", func(c context.Context) error {
+		return &HTTPError{Code: 400, Detail: "bad input"}
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if code, want := w.Code, 400; code != want {
+		t.Errorf("w.Code = %v; want %v", code, want)
+	}
+
+	if body, want := w.Body.String(), "bad input"; body != want {
+		t.Errorf("w.Body = %v; want %v", body, want)
+	}
+}
+
+func TestHTTPErrorInternal(t *testing.T) {
+	cause := errors.New("db: connection refused")
+	var out bytes.Buffer
+	h := New()
+	h.Logger = log.New(log.ErrOut(&out))
+	h.GET("/", func(c context.Context) error {
+		err := NewHTTPError(http.StatusServiceUnavailable, "").WithInternal(cause)
+		return fmt.Errorf("load user: %w", err)
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if code, want := w.Code, http.StatusServiceUnavailable; code != want {
+		t.Errorf("w.Code = %v; want %v", code, want)
+	}
+
+	if body, want := w.Body.String(), "Service Unavailable"; body != want {
+		t.Errorf("w.Body = %v; want %v", body, want)
+	}
+
+	if got, want := out.String(), "[ERROR] 503 Service Unavailable: db: connection refused\n"; got != want {
+		t.Errorf("log = %q; want %q", got, want)
+	}
+
+	he := NewHTTPError(http.StatusBadGateway, "upstream").WithInternal(cause)
+	if !errors.Is(he, cause) {
+		t.Errorf("errors.Is(%v, %v) = false", he, cause)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	var out bytes.Buffer
+	var recovered interface{}
+	h := New()
+	h.Logger = log.New(log.ErrOut(&out))
+	h.PanicHandler = func(c context.Context, v interface{}, stack []byte) {
+		recovered = v
+	}
+	h.GET("/", func(c context.Context) error {
+		panic("boom")
+	})
+
+	for i := 0; i < 2; i++ {
+		r, _ := http.NewRequest("GET", "/", nil)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if code, want := w.Code, 500; code != want {
+			t.Errorf("w.Code = %v; want %v", code, want)
+		}
+
+		if body, want := w.Body.String(), http.StatusText(500); body != want {
+			t.Errorf("w.Body = %v; want %v", body, want)
+		}
+	}
+
+	if want := "boom"; recovered != want {
+		t.Errorf("recovered = %v; want %v", recovered, want)
+	}
+
+	if got := out.String(); !strings.HasPrefix(got, "[ERROR] panic: boom\ngoroutine ") {
+		t.Errorf("log = %q; want the stack", got)
+	}
+}
+
+func TestPanicAbortHandler(t *testing.T) {
+	var ctx context.Context
+	h := New()
+	h.GET("/", func(c context.Context) error {
+		ctx = c
+		panic(http.ErrAbortHandler)
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	func() {
+		defer func() {
+			if v := recover(); v != http.ErrAbortHandler {
+				t.Errorf("recover() = %v; want %v", v, http.ErrAbortHandler)
+			}
+		}()
+		h.ServeHTTP(w, r)
+	}()
+
+	if ctx.Err() != context.Canceled {
+		t.Errorf("ctx.Err() = %v; want %v", ctx.Err(), context.Canceled)
+	}
+}
+
+func TestCommittedError(t *testing.T) {
+	var out bytes.Buffer
+	h := New()
+	h.Logger = log.New(log.ErrOut(&out))
+	h.GET("/", func(c context.Context) error {
+		Text(c, 200, "partial")
+		return errors.New("Test")
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if code, want := w.Code, 200; code != want {
+		t.Errorf("w.Code = %v; want %v", code, want)
+	}
+
+	if body, want := w.Body.String(), "partial"; body != want {
+		t.Errorf("w.Body = %v; want %v", body, want)
+	}
+
+	if got, want := out.String(), "[ERROR] Test: response already committed with status 200 and 7 bytes\n"; got != want {
+		t.Errorf("log = %q; want %q", got, want)
+	}
+}
+
+func TestAbortOnCommittedError(t *testing.T) {
+	h := New()
+	h.Logger = log.New(log.ErrOut(io.Discard))
+	h.AbortOnCommittedError = true
+	h.GET("/", func(c context.Context) error {
+		return JSONStream(c, 200, func(yield func(v interface{}) error) error {
+			if err := yield(1); err != nil {
+				return err
+			}
+			Response(c).Flush()
+			return errors.New("Test")
+		})
+	})
+	s := httptest.NewServer(h)
+	defer s.Close()
+
+	res, err := http.Get(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if _, err := io.ReadAll(res.Body); err != io.ErrUnexpectedEOF {
+		t.Errorf("err = %v; want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
 func TestMiddleware(t *testing.T) {
 	var res []int
 	h := New()
@@ -181,6 +353,73 @@ func TestMiddlewareNoHandler(t *testing.T) {
 	}
 }
 
+func TestHandle(t *testing.T) {
+	h := New()
+	h.Handle("PROPFIND", "/dav", func(c context.Context) error {
+		return Text(c, 207, "propfind")
+	})
+	h.Any("/any", func(c context.Context) error {
+		return Text(c, 200, Request(c).Method)
+	})
+	h.Match([]string{"PUT", "PURGE"}, "/match", func(c context.Context) error {
+		return Text(c, 200, Request(c).Method)
+	})
+
+	tests := []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{"PROPFIND", "/dav", 207, "propfind"},
+		{"GET", "/dav", 405, "Method Not Allowed"},
+		{"GET", "/any", 200, "GET"},
+		{"DELETE", "/any", 200, "DELETE"},
+		{"TRACE", "/any", 200, "TRACE"},
+		{"PURGE", "/match", 200, "PURGE"},
+		{"PUT", "/match", 200, "PUT"},
+		{"GET", "/match", 405, "Method Not Allowed"},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest(tt.method, tt.path, nil)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if code := w.Code; code != tt.code {
+			t.Errorf("%s %s: w.Code = %v; want %v", tt.method, tt.path, code, tt.code)
+		}
+
+		if body := w.Body.String(); body != tt.body {
+			t.Errorf("%s %s: w.Body = %v; want %v", tt.method, tt.path, body, tt.body)
+		}
+	}
+}
+
+func TestUseAfterRegister(t *testing.T) {
+	var called int
+	h := New()
+	h.GET("/", func(c context.Context) error {
+		return NoContent(c, 204)
+	})
+	h.Use(func(next HandlerFunc) HandlerFunc {
+		return func(c context.Context) error {
+			called++
+			return next(c)
+		}
+	})
+
+	for i := 0; i < 2; i++ {
+		r, _ := http.NewRequest("GET", "/", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+	}
+
+	if want := 2; called != want {
+		t.Errorf("called = %v; want %v", called, want)
+	}
+}
+
 type mockResponseWriter struct {
 }
 
@@ -236,3 +475,19 @@ func BenchmarkRequestParam(b *testing.B) {
 	req, _ := http.NewRequest("GET", "/gopher", nil)
 	benchRequest(b, testHandler, req)
 }
+
+func BenchmarkRequestMiddleware(b *testing.B) {
+	h := New()
+	mw := func(next HandlerFunc) HandlerFunc {
+		return func(c context.Context) error {
+			return next(c)
+		}
+	}
+	h.Use(mw, mw)
+	h.GET("/", func(c context.Context) error {
+		return Text(c, 200, "Hello, World")
+	}, mw, mw, mw)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	benchRequest(b, h, req)
+}
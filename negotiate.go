@@ -0,0 +1,259 @@
+package horo
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+type (
+	// EncoderFunc encodes v into w for Negotiate.
+	EncoderFunc func(c context.Context, w io.Writer, v interface{}) error
+
+	// Template is a HTML template view for Negotiate.
+	// Horo.Renderer renders Name if Template is nil.
+	// Other encoders encode Data.
+	Template struct {
+		Template *template.Template
+		Name     string
+		Data     interface{}
+	}
+
+	encoder struct {
+		mediaType string
+		fn        EncoderFunc
+	}
+
+	acceptRange struct {
+		typ, sub string
+		q        float64
+	}
+)
+
+const (
+	// MIMEApplicationJSON is JSON media type.
+	MIMEApplicationJSON = "application/json"
+
+	// MIMEApplicationJSONCharsetUTF8 is the Content-Type of JSON responses.
+	MIMEApplicationJSONCharsetUTF8 = MIMEApplicationJSON + "; charset=utf-8"
+
+	// MIMEApplicationXML is XML media type.
+	MIMEApplicationXML = "application/xml"
+
+	// MIMETextPlain is plain text media type.
+	MIMETextPlain = "text/plain"
+
+	// MIMETextHTML is HTML media type.
+	MIMETextHTML = "text/html"
+)
+
+func defaultEncoders() []encoder {
+	return []encoder{
+		{MIMEApplicationJSON, encodeJSON},
+		{MIMEApplicationXML, encodeXML},
+		{MIMETextPlain, encodeText},
+		{MIMETextHTML, encodeHTML},
+	}
+}
+
+// RegisterEncoder registers the encoder for mediaType used by Negotiate.
+// It replaces the encoder if mediaType is already registered.
+func (h *Horo) RegisterEncoder(mediaType string, fn EncoderFunc) {
+	for i, e := range h.encoders {
+		if e.mediaType == mediaType {
+			h.encoders[i].fn = fn
+			return
+		}
+	}
+	h.encoders = append(h.encoders, encoder{mediaType, fn})
+}
+
+// Negotiate send a response in the format selected by the Accept header.
+// offers are registered media types in order of preference; all
+// registered encoders are offered if empty.
+// It returns a 406 *HTTPError if nothing matches.
+func Negotiate(c context.Context, code int, v interface{}, offers ...string) error {
+	hc := fromCtx(c)
+	if hc == nil {
+		return ErrNotContext
+	}
+
+	if len(offers) == 0 {
+		offers = make([]string, len(hc.h.encoders))
+		for i, e := range hc.h.encoders {
+			offers[i] = e.mediaType
+		}
+	}
+
+	hc.w.Header().Add("Vary", "Accept")
+
+	mt := negotiate(hc.r.Header.Get("Accept"), offers)
+	var fn EncoderFunc
+	for _, e := range hc.h.encoders {
+		if e.mediaType == mt {
+			fn = e.fn
+			break
+		}
+	}
+	if fn == nil {
+		return &HTTPError{
+			Code:    http.StatusNotAcceptable,
+			Message: http.StatusText(http.StatusNotAcceptable),
+		}
+	}
+
+	b := getBuffer()
+	defer putBuffer(b)
+	if err := fn(c, b, v); err != nil {
+		return err
+	}
+
+	ct := mt
+	if strings.HasPrefix(mt, "text/") || mt == MIMEApplicationJSON || mt == MIMEApplicationXML {
+		ct += "; charset=utf-8"
+	}
+	return writeBuffer(c, code, ct, b)
+}
+
+// negotiate returns the offer best matched with accept.
+func negotiate(accept string, offers []string) string {
+	if accept == "" {
+		if len(offers) > 0 {
+			return offers[0]
+		}
+		return ""
+	}
+
+	ranges := parseAccept(accept)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		typ, sub := splitMediaType(offer)
+		q, spec := 0.0, -1
+		for _, ar := range ranges {
+			s := -1
+			switch {
+			case ar.typ == typ && ar.sub == sub:
+				s = 2
+			case ar.typ == typ && ar.sub == "*":
+				s = 1
+			case ar.typ == "*" && ar.sub == "*":
+				s = 0
+			}
+			if s > spec {
+				q, spec = ar.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, s := range strings.Split(accept, ",") {
+		parts := strings.Split(s, ";")
+		typ, sub := splitMediaType(strings.TrimSpace(parts[0]))
+		if typ == "" {
+			continue
+		}
+		ar := acceptRange{typ: typ, sub: sub, q: 1}
+		for _, p := range parts[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if q, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					ar.q = q
+				}
+			}
+		}
+		ranges = append(ranges, ar)
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+	return ranges
+}
+
+func splitMediaType(mt string) (string, string) {
+	mt = strings.ToLower(mt)
+	i := strings.IndexByte(mt, '/')
+	if i < 0 {
+		if mt == "*" {
+			return "*", "*"
+		}
+		return "", ""
+	}
+	return mt[:i], mt[i+1:]
+}
+
+// viewData returns Template data, or v itself.
+func viewData(v interface{}) interface{} {
+	switch t := v.(type) {
+	case Template:
+		return t.Data
+	case *Template:
+		return t.Data
+	}
+	return v
+}
+
+func encodeJSON(c context.Context, w io.Writer, v interface{}) error {
+	if b, ok := w.(*bytes.Buffer); ok {
+		return encodeJSONTo(b, viewData(v), "")
+	}
+	return json.NewEncoder(w).Encode(viewData(v))
+}
+
+func encodeXML(c context.Context, w io.Writer, v interface{}) error {
+	return xml.NewEncoder(w).Encode(viewData(v))
+}
+
+func encodeText(c context.Context, w io.Writer, v interface{}) (err error) {
+	switch d := viewData(v).(type) {
+	case string:
+		_, err = io.WriteString(w, d)
+	case []byte:
+		_, err = w.Write(d)
+	default:
+		_, err = fmt.Fprint(w, d)
+	}
+	return
+}
+
+func encodeHTML(c context.Context, w io.Writer, v interface{}) (err error) {
+	if t, ok := v.(Template); ok {
+		v = &t
+	}
+	switch d := v.(type) {
+	case *Template:
+		if d.Template == nil {
+			hc := fromCtx(c)
+			if hc == nil {
+				return ErrNotContext
+			}
+			if hc.h.Renderer == nil {
+				return ErrNoRenderer
+			}
+			return hc.h.Renderer.Render(c, w, d.Name, d.Data)
+		}
+		if d.Name != "" {
+			return d.Template.ExecuteTemplate(w, d.Name, d.Data)
+		}
+		return d.Template.Execute(w, d.Data)
+	case template.HTML:
+		_, err = io.WriteString(w, string(d))
+	default:
+		_, err = io.WriteString(w, template.HTMLEscapeString(fmt.Sprint(d)))
+	}
+	return
+}
@@ -0,0 +1,76 @@
+package horo
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+type negotiateUser struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func (u negotiateUser) String() string {
+	return "user " + u.Name
+}
+
+func TestNegotiate(t *testing.T) {
+	tmpl := template.Must(template.New("user").Parse(`<p>{{.Name}}</p>`))
+	h := New()
+	h.RegisterEncoder("text/csv", func(c context.Context, w io.Writer, v interface{}) error {
+		_, err := fmt.Fprintf(w, "name\n%s\n", viewData(v).(negotiateUser).Name)
+		return err
+	})
+	h.GET("/", func(c context.Context) error {
+		return Negotiate(c, 200, &Template{Template: tmpl, Data: negotiateUser{"<gopher>"}})
+	})
+	h.GET("/api", func(c context.Context) error {
+		return Negotiate(c, 200, negotiateUser{"gopher"}, MIMEApplicationJSON, MIMEApplicationXML)
+	})
+
+	tests := []struct {
+		path, accept string
+		code         int
+		contentType  string
+		body         string
+	}{
+		{"/", "", 200, "application/json; charset=utf-8", `{"name":"\u003cgopher\u003e"}`},
+		{"/", "text/html,application/xhtml+xml,*/*;q=0.8", 200, "text/html; charset=utf-8", "<p>&lt;gopher&gt;</p>"},
+		{"/", "application/xml;q=0.9, text/plain", 200, "text/plain; charset=utf-8", "user <gopher>"},
+		{"/", "text/*;q=0.5, application/xml", 200, "application/xml; charset=utf-8", "<negotiateUser><name>&lt;gopher&gt;</name></negotiateUser>"},
+		{"/", "text/csv", 200, "text/csv; charset=utf-8", "name\n<gopher>\n"},
+		{"/", "text/*, text/plain;q=0", 200, "text/html; charset=utf-8", "<p>&lt;gopher&gt;</p>"},
+		{"/api", "*/*", 200, "application/json; charset=utf-8", `{"name":"gopher"}`},
+		{"/api", "application/*;q=0.5, application/xml", 200, "application/xml; charset=utf-8", "<negotiateUser><name>gopher</name></negotiateUser>"},
+		{"/api", "text/html", 406, "text/plain", "Not Acceptable"},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", tt.path, nil)
+		r.Header.Set("Accept", tt.accept)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if code := w.Code; code != tt.code {
+			t.Errorf("%s %q: w.Code = %v; want %v", tt.path, tt.accept, code, tt.code)
+		}
+
+		if ct := w.Header().Get("Content-Type"); ct != tt.contentType {
+			t.Errorf("%s %q: Content-Type = %v; want %v", tt.path, tt.accept, ct, tt.contentType)
+		}
+
+		if vary, want := w.Header().Get("Vary"), "Accept"; vary != want {
+			t.Errorf("%s %q: Vary = %v; want %v", tt.path, tt.accept, vary, want)
+		}
+
+		if body := w.Body.String(); body != tt.body {
+			t.Errorf("%s %q: w.Body = %v; want %v", tt.path, tt.accept, body, tt.body)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package horo
+
+import (
+	"errors"
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// MIMEApplicationProblemJSON is RFC 7807 problem details media type.
+	MIMEApplicationProblemJSON = "application/problem+json"
+)
+
+// Problem returns RFC 7807 problem details of err.
+// Extension members of *HTTPError are added, and the request ID is added
+// as requestId. A *ValidationError adds fields.
+func Problem(c context.Context, err error) map[string]interface{} {
+	p := map[string]interface{}{}
+	code, title, detail := http.StatusInternalServerError, "", ""
+	var he *HTTPError
+	var ve *ValidationError
+	switch {
+	case errors.As(err, &he):
+		for k, v := range he.Extensions {
+			p[k] = v
+		}
+		code, title, detail = he.Code, he.Title, he.Detail
+		if detail == "" && he.Message != http.StatusText(code) {
+			detail = he.Message
+		}
+		if he.Type != "" {
+			p["type"] = he.Type
+		}
+		if he.Instance != "" {
+			p["instance"] = he.Instance
+		}
+	case errors.As(err, &ve):
+		code, detail = http.StatusUnprocessableEntity, ve.Error()
+		p["fields"] = ve.Fields
+	}
+
+	if _, ok := p["type"].(string); !ok {
+		p["type"] = "about:blank"
+	}
+	if title == "" {
+		title = http.StatusText(code)
+	}
+	p["title"] = title
+	p["status"] = code
+	if detail != "" {
+		p["detail"] = detail
+	} else {
+		delete(p, "detail")
+	}
+	if id := RequestID(c); id != "" {
+		p["requestId"] = id
+	}
+	return p
+}
+
+// writeProblem writes err as problem+json, JSON or text by the Accept header.
+func writeProblem(c context.Context, err error) {
+	hc := fromCtx(c)
+	p := Problem(c, err)
+	code := p["status"].(int)
+
+	hc.w.Header().Add("Vary", "Accept")
+
+	offers := []string{MIMEApplicationProblemJSON, MIMEApplicationJSON, MIMETextPlain}
+	switch negotiate(hc.r.Header.Get("Accept"), offers) {
+	case MIMEApplicationJSON:
+		jsonBlob(c, code, MIMEApplicationJSONCharsetUTF8, p, "")
+	case MIMETextPlain:
+		msg := p["title"].(string)
+		if d, ok := p["detail"].(string); ok {
+			msg = d
+		}
+		Text(c, code, msg)
+	default:
+		jsonBlob(c, code, MIMEApplicationProblemJSON, p, "")
+	}
+}
@@ -0,0 +1,91 @@
+package horo
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestProblemDetails(t *testing.T) {
+	h := New()
+	h.ProblemDetails = true
+	h.RequestIDGenerator = staticRequestID("req-1")
+	h.GET("/balance", func(c context.Context) error {
+		return &HTTPError{
+			Code:       http.StatusForbidden,
+			Message:    "Your current balance is 30, but that costs 50.",
+			Type:       "https://example.com/probs/out-of-credit",
+			Title:      "You do not have enough credit.",
+			Instance:   "/account/12345/msgs/abc",
+			Extensions: map[string]interface{}{"balance": 30, "status": 0},
+		}
+	})
+	h.GET("/error", func(c context.Context) error {
+		return errors.New("secret")
+	})
+
+	tests := []struct {
+		path   string
+		accept string
+		code   int
+		ct     string
+		body   string
+	}{
+		{"/balance", "", http.StatusForbidden, "application/problem+json",
+			`{"balance":30,"detail":"Your current balance is 30, but that costs 50.","instance":"/account/12345/msgs/abc","requestId":"req-1","status":403,"title":"You do not have enough credit.","type":"https://example.com/probs/out-of-credit"}`},
+		{"/error", "application/json", http.StatusInternalServerError, "application/json; charset=utf-8",
+			`{"requestId":"req-1","status":500,"title":"Internal Server Error","type":"about:blank"}`},
+		{"/error", "text/plain", http.StatusInternalServerError, "text/plain",
+			"Internal Server Error"},
+		{"/balance", "text/html, text/plain;q=0.5", http.StatusForbidden, "text/plain",
+			"Your current balance is 30, but that costs 50."},
+		{"/missing", "application/problem+json", http.StatusNotFound, "application/problem+json",
+			`{"requestId":"req-1","status":404,"title":"Not Found","type":"about:blank"}`},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", tt.path, nil)
+		r.Header.Set("Accept", tt.accept)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if code := w.Code; code != tt.code {
+			t.Errorf("%s %q: w.Code = %v; want %v", tt.path, tt.accept, code, tt.code)
+		}
+
+		if ct := w.Header().Get("Content-Type"); ct != tt.ct {
+			t.Errorf("%s %q: Content-Type = %v; want %v", tt.path, tt.accept, ct, tt.ct)
+		}
+
+		if body := w.Body.String(); body != tt.body {
+			t.Errorf("%s %q: w.Body = %v; want %v", tt.path, tt.accept, body, tt.body)
+		}
+	}
+}
+
+func TestProblemValidation(t *testing.T) {
+	h := New()
+	h.ProblemDetails = true
+	h.RequestIDGenerator = staticRequestID("req-1")
+	h.GET("/", func(c context.Context) error {
+		return &ValidationError{Fields: map[string]string{"name": "is required"}}
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if code, want := w.Code, http.StatusUnprocessableEntity; code != want {
+		t.Errorf("w.Code = %v; want %v", code, want)
+	}
+
+	want := `{"detail":"validation failed: name is required","fields":{"name":"is required"},"requestId":"req-1","status":422,"title":"Unprocessable Entity","type":"about:blank"}`
+	if body := w.Body.String(); body != want {
+		t.Errorf("w.Body = %v; want %v", body, want)
+	}
+}
@@ -0,0 +1,213 @@
+package horo
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"path"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+type (
+	// Renderer renders the named template.
+	Renderer interface {
+		Render(c context.Context, w io.Writer, name string, data interface{}) error
+	}
+
+	// TemplateRenderer is html/template Renderer loading templates from fs.FS.
+	//
+	// Each page is parsed with the layout and partials, and escaped once.
+	// Templates can call url, which returns the path of a named route, and
+	// requestID. They take the request context from the data, such as
+	// {{url $.ctx "user" .ID}} and {{requestID $.ctx}}.
+	TemplateRenderer struct {
+		fsys  fs.FS
+		pages string
+		o     *templateOpts
+
+		mu        sync.RWMutex
+		templates map[string]*template.Template
+	}
+
+	// TemplateOption is NewTemplateRenderer option.
+	TemplateOption func(*templateOpts)
+
+	templateOpts struct {
+		layout   string
+		partials []string
+		funcs    template.FuncMap
+		dev      bool
+	}
+)
+
+var (
+	// ErrNoRenderer is thrown if Horo.Renderer is not set.
+	ErrNoRenderer = errors.New("renderer is not set")
+)
+
+// Layout sets the layout file. The layout is executed instead of the page,
+// so the page defines templates used by the layout.
+func Layout(file string) TemplateOption {
+	return func(o *templateOpts) {
+		o.layout = file
+	}
+}
+
+// Partials adds glob patterns of partial files shared by all pages.
+func Partials(patterns ...string) TemplateOption {
+	return func(o *templateOpts) {
+		o.partials = append(o.partials, patterns...)
+	}
+}
+
+// Funcs adds template funcs.
+func Funcs(funcs template.FuncMap) TemplateOption {
+	return func(o *templateOpts) {
+		for k, f := range funcs {
+			o.funcs[k] = f
+		}
+	}
+}
+
+// DevMode re-parses templates on each render.
+func DevMode() TemplateOption {
+	return func(o *templateOpts) {
+		o.dev = true
+	}
+}
+
+// NewTemplateRenderer returns TemplateRenderer for page files matching
+// the glob pattern in fsys. Pages are named by the path in fsys.
+func NewTemplateRenderer(fsys fs.FS, pages string, opt ...TemplateOption) (*TemplateRenderer, error) {
+	o := &templateOpts{funcs: template.FuncMap{}}
+	for _, f := range opt {
+		f(o)
+	}
+
+	r := &TemplateRenderer{
+		fsys:  fsys,
+		pages: pages,
+		o:     o,
+	}
+	if err := r.parseAll(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Render implements Renderer.
+func (r *TemplateRenderer) Render(c context.Context, w io.Writer, name string, data interface{}) error {
+	t, err := r.lookup(name)
+	if err != nil {
+		return err
+	}
+
+	return t.ExecuteTemplate(w, r.entry(name), data)
+}
+
+func (r *TemplateRenderer) lookup(name string) (*template.Template, error) {
+	if r.o.dev {
+		return r.parse(name)
+	}
+
+	r.mu.RLock()
+	t, ok := r.templates[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("horo: template %q not found", name)
+	}
+	return t, nil
+}
+
+func (r *TemplateRenderer) entry(name string) string {
+	if r.o.layout != "" {
+		return path.Base(r.o.layout)
+	}
+	return path.Base(name)
+}
+
+func (r *TemplateRenderer) parseAll() error {
+	names, err := fs.Glob(r.fsys, r.pages)
+	if err != nil {
+		return err
+	}
+
+	ts := make(map[string]*template.Template, len(names))
+	for _, name := range names {
+		t, err := r.parse(name)
+		if err != nil {
+			return err
+		}
+		ts[name] = t
+	}
+
+	r.mu.Lock()
+	r.templates = ts
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *TemplateRenderer) parse(name string) (*template.Template, error) {
+	if _, err := fs.Stat(r.fsys, name); err != nil {
+		return nil, fmt.Errorf("horo: template %q not found", name)
+	}
+
+	t := template.New(path.Base(name)).Funcs(template.FuncMap{
+		"url":       templateURL,
+		"requestID": templateRequestID,
+	}).Funcs(r.o.funcs)
+
+	var patterns []string
+	if r.o.layout != "" {
+		patterns = append(patterns, r.o.layout)
+	}
+	patterns = append(patterns, r.o.partials...)
+	for _, p := range patterns {
+		m, err := fs.Glob(r.fsys, p)
+		if err != nil {
+			return nil, err
+		}
+		if len(m) == 0 {
+			continue
+		}
+		if t, err = t.ParseFS(r.fsys, m...); err != nil {
+			return nil, err
+		}
+	}
+
+	return t.ParseFS(r.fsys, name)
+}
+
+func templateURL(c context.Context, name string, params ...interface{}) (string, error) {
+	if c == nil {
+		return "", ErrNotContext
+	}
+	return URL(c, name, params...)
+}
+
+func templateRequestID(c context.Context) string {
+	if c == nil {
+		return ""
+	}
+	return RequestID(c)
+}
+
+// Render send a HTML response rendered by Horo.Renderer.
+func Render(c context.Context, code int, name string, data interface{}) (err error) {
+	if hc := fromCtx(c); hc != nil {
+		if hc.h.Renderer == nil {
+			return ErrNoRenderer
+		}
+		b := getBuffer()
+		defer putBuffer(b)
+		if err = hc.h.Renderer.Render(c, b, name, data); err != nil {
+			return
+		}
+		return writeBuffer(c, code, "text/html; charset=utf-8", b)
+	}
+	return ErrNotContext
+}
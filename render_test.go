@@ -0,0 +1,149 @@
+package horo
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"golang.org/x/net/context"
+)
+
+type staticRequestID string
+
+func (id staticRequestID) RequestID(c context.Context) string {
+	return string(id)
+}
+
+func TestRender(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/base.html":    {Data: []byte(`<title>{{template "title" .}}</title>{{template "content" .}}{{template "footer.html" .}}`)},
+		"partials/footer.html": {Data: []byte(`<footer>{{requestID $.ctx}}</footer>`)},
+		"pages/user.html":      {Data: []byte(`{{define "title"}}{{upper .Name}}{{end}}{{define "content"}}<a href="{{url $.ctx "user" .ID}}">{{.Name}}</a>{{end}}`)},
+	}
+
+	renderer, err := NewTemplateRenderer(fsys, "pages/*.html",
+		Layout("layouts/base.html"),
+		Partials("partials/*.html"),
+		Funcs(template.FuncMap{"upper": strings.ToUpper}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := New()
+	h.Renderer = renderer
+	h.RequestIDGenerator = staticRequestID("req-1")
+	h.GET("/users/:id", func(c context.Context) error {
+		return Render(c, 200, "pages/user.html", map[string]interface{}{
+			"ctx":  c,
+			"ID":   Param(c, "id"),
+			"Name": "<gopher>",
+		})
+	}).Name("user")
+	h.GET("/missing", func(c context.Context) error {
+		return Render(c, 200, "pages/missing.html", nil)
+	})
+
+	r, _ := http.NewRequest("GET", "/users/1", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if ct, want := w.Header().Get("Content-Type"), "text/html; charset=utf-8"; ct != want {
+		t.Errorf("Content-Type = %v; want %v", ct, want)
+	}
+
+	want := `<title>&lt;GOPHER&gt;</title><a href="/users/1">&lt;gopher&gt;</a><footer>req-1</footer>`
+	if body := w.Body.String(); body != want {
+		t.Errorf("w.Body = %v; want %v", body, want)
+	}
+
+	r, _ = http.NewRequest("GET", "/missing", nil)
+	w = httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if code, want := w.Code, 500; code != want {
+		t.Errorf("w.Code = %v; want %v", code, want)
+	}
+}
+
+func TestRenderDevMode(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte(`v1`)},
+	}
+
+	renderer, err := NewTemplateRenderer(fsys, "*.html", DevMode())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := New()
+	h.Renderer = renderer
+	h.GET("/", func(c context.Context) error {
+		return Render(c, 200, "index.html", nil)
+	})
+
+	for _, v := range []string{"v1", "v2"} {
+		fsys["index.html"] = &fstest.MapFile{Data: []byte(v)}
+
+		r, _ := http.NewRequest("GET", "/", nil)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if body := w.Body.String(); body != v {
+			t.Errorf("w.Body = %v; want %v", body, v)
+		}
+	}
+}
+
+func TestNegotiateRenderer(t *testing.T) {
+	renderer, err := NewTemplateRenderer(fstest.MapFS{
+		"user.html": {Data: []byte(`<p>{{.Name}}</p>`)},
+	}, "*.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := New()
+	h.Renderer = renderer
+	h.GET("/", func(c context.Context) error {
+		return Negotiate(c, 200, &Template{Name: "user.html", Data: negotiateUser{"gopher"}})
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set("Accept", "text/html")
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if body, want := w.Body.String(), "<p>gopher</p>"; body != want {
+		t.Errorf("w.Body = %v; want %v", body, want)
+	}
+}
+
+func BenchmarkRender(b *testing.B) {
+	renderer, err := NewTemplateRenderer(fstest.MapFS{
+		"user.html": {Data: []byte(`<a href="{{url $.ctx "user" .ID}}">{{.Name}}</a>`)},
+	}, "*.html")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	h := New()
+	h.Renderer = renderer
+	h.GET("/users/:id", func(c context.Context) error {
+		return Render(c, 200, "user.html", map[string]interface{}{
+			"ctx":  c,
+			"ID":   Param(c, "id"),
+			"Name": "gopher",
+		})
+	}).Name("user")
+
+	req, _ := http.NewRequest("GET", "/users/1", nil)
+	benchRequest(b, h, req)
+}
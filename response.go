@@ -15,6 +15,9 @@ type (
 		code      int
 		size      int64
 		committed bool
+
+		// forceCode replaces the status written by WriteHeader if not 0.
+		forceCode int
 	}
 )
 
@@ -46,19 +49,34 @@ func (r *ResponseWriter) WriteHeader(code int) {
 	if r.committed {
 		return
 	}
+	if r.forceCode != 0 {
+		code = r.forceCode
+	}
 	r.code = code
 	r.rw.WriteHeader(code)
 	r.committed = true
 }
 
 // Hijack implements http.Hijacker
+// The response is committed after hijacking.
 func (r *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
-	return r.rw.(http.Hijacker).Hijack()
+	h, ok := r.rw.(http.Hijacker)
+	if !ok {
+		return nil, nil, ErrNotHijacker
+	}
+	conn, brw, err := h.Hijack()
+	if err == nil {
+		r.committed = true
+	}
+	return conn, brw, err
 }
 
 // Flush implements http.Flusher
+// It does nothing if the underlying http.ResponseWriter is not http.Flusher.
 func (r *ResponseWriter) Flush() {
-	r.rw.(http.Flusher).Flush()
+	if f, ok := r.rw.(http.Flusher); ok {
+		f.Flush()
+	}
 }
 
 // CloseNotify implements http.CloseNotifier
@@ -87,4 +105,5 @@ func (r *ResponseWriter) Reset(w http.ResponseWriter) {
 	r.size = 0
 	r.code = 0
 	r.committed = false
+	r.forceCode = 0
 }
@@ -0,0 +1,238 @@
+package horo
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/net/context"
+)
+
+type (
+	// Route is a registered handler.
+	Route struct {
+		h      *Horo
+		group  *Group
+		method string
+		path   string
+		name   string
+		hf     HandlerFunc
+		mw     []MiddlewareFunc
+		chain  HandlerFunc
+
+		constraints []constraint
+	}
+
+	// RouteInfo describes a registered route.
+	RouteInfo struct {
+		Method      string
+		Path        string
+		Name        string
+		Handler     string
+		Middleware  int
+		Constraints map[string]string
+	}
+
+	// constraint is a param pattern.
+	constraint struct {
+		name    string
+		pattern string
+		re      *regexp.Regexp
+	}
+)
+
+var (
+	// ErrRouteNotFound is thrown if the named route is not registered.
+	ErrRouteNotFound = errors.New("route not found")
+
+	constraintPatterns = map[string]string{
+		"int":  `-?[0-9]+`,
+		"uuid": `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+	}
+)
+
+func (h *Horo) newRoute(g *Group, hf HandlerFunc, mw ...MiddlewareFunc) *Route {
+	rt := &Route{h: h, group: g, hf: hf, mw: mw}
+	rt.build(h.middleware)
+	return rt
+}
+
+// build wraps the handler with global, group and route middleware.
+func (rt *Route) build(global []MiddlewareFunc) {
+	hf := rt.hf
+	for i := len(rt.mw) - 1; i >= 0; i-- {
+		hf = rt.mw[i](hf)
+	}
+	group := rt.group.stack()
+	for i := len(group) - 1; i >= 0; i-- {
+		hf = group[i](hf)
+	}
+	for i := len(global) - 1; i >= 0; i-- {
+		hf = global[i](hf)
+	}
+	rt.chain = hf
+}
+
+// Name sets the route name used by Reverse and URL.
+// It panics if the name is already used by another route.
+func (rt *Route) Name(name string) *Route {
+	if r, ok := rt.h.names[name]; ok && r != rt {
+		panic("horo: route name '" + name + "' is already registered")
+	}
+	if rt.name != "" {
+		delete(rt.h.names, rt.name)
+	}
+	rt.name = name
+	rt.h.names[name] = rt
+	return rt
+}
+
+// Routes returns registered routes in registration order.
+// Single-page app fallbacks, files and a handler at the root, served for
+// requests no route matches, follow with method "*".
+func (h *Horo) Routes() []RouteInfo {
+	rts := h.routes
+	for _, fb := range h.fallbacks {
+		rts = append(rts[:len(rts):len(rts)], fb.rt)
+	}
+	if h.mounted != nil {
+		rts = append(rts[:len(rts):len(rts)], h.mounted)
+	}
+
+	ri := make([]RouteInfo, len(rts))
+	for i, rt := range rts {
+		ri[i] = RouteInfo{
+			Method:     rt.method,
+			Path:       rt.path,
+			Name:       rt.name,
+			Handler:    funcName(rt.hf),
+			Middleware: len(h.middleware) + len(rt.group.stack()) + len(rt.mw),
+		}
+		if len(rt.constraints) > 0 {
+			ri[i].Constraints = make(map[string]string, len(rt.constraints))
+			for _, ct := range rt.constraints {
+				ri[i].Constraints[ct.name] = ct.pattern
+			}
+		}
+	}
+	return ri
+}
+
+// String returns a route table line.
+func (ri RouteInfo) String() string {
+	return fmt.Sprintf("%-7s %s -> %s (%d middleware)", ri.Method, ri.Path, ri.Handler, ri.Middleware)
+}
+
+func funcName(f interface{}) string {
+	v := reflect.ValueOf(f)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return ""
+	}
+	if fn := runtime.FuncForPC(v.Pointer()); fn != nil {
+		return fn.Name()
+	}
+	return ""
+}
+
+// Reverse returns the path of the named route.
+// params fill the :param and *catchall segments in order.
+func (h *Horo) Reverse(name string, params ...interface{}) (string, error) {
+	rt, ok := h.names[name]
+	if !ok {
+		return "", ErrRouteNotFound
+	}
+	return rt.reverse(params)
+}
+
+// URL returns the path of the named route from context.
+func URL(c context.Context, name string, params ...interface{}) (string, error) {
+	if c := fromCtx(c); c != nil {
+		return c.h.Reverse(name, params...)
+	}
+	return "", ErrNotContext
+}
+
+// parseConstraints returns the router path and constraints of path.
+// It panics if a constraint is not valid regexp.
+func parseConstraints(path string) (string, []constraint) {
+	if !strings.Contains(path, "{") {
+		return path, nil
+	}
+
+	var cs []constraint
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		if seg == "" || seg[0] != ':' || !strings.HasSuffix(seg, "}") {
+			continue
+		}
+		b := strings.IndexByte(seg, '{')
+		if b < 0 {
+			continue
+		}
+
+		name, pattern := seg[1:b], seg[b+1:len(seg)-1]
+		expr, ok := constraintPatterns[pattern]
+		if !ok {
+			expr = pattern
+		}
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			panic("horo: invalid constraint for param '" + name + "' in path '" + path + "': " + err.Error())
+		}
+
+		cs = append(cs, constraint{name: name, pattern: pattern, re: re})
+		segs[i] = ":" + name
+	}
+	return strings.Join(segs, "/"), cs
+}
+
+// match reports whether ps satisfy the route constraints.
+func (rt *Route) match(ps httprouter.Params) bool {
+	for _, ct := range rt.constraints {
+		if !ct.re.MatchString(ps.ByName(ct.name)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (rt *Route) reverse(params []interface{}) (string, error) {
+	path := rt.path
+	segs := strings.Split(path, "/")
+	n := 0
+	for i, seg := range segs {
+		if seg == "" || (seg[0] != ':' && seg[0] != '*') {
+			continue
+		}
+		if n >= len(params) {
+			return "", fmt.Errorf("horo: missing param %q for path %q", seg[1:], path)
+		}
+		v := fmt.Sprint(params[n])
+		n++
+
+		if seg[0] == ':' {
+			for _, ct := range rt.constraints {
+				if ct.name == seg[1:] && !ct.re.MatchString(v) {
+					return "", fmt.Errorf("horo: param %q does not match {%s} for path %q", ct.name, ct.pattern, path)
+				}
+			}
+			segs[i] = url.PathEscape(v)
+			continue
+		}
+
+		vs := strings.Split(strings.TrimPrefix(v, "/"), "/")
+		for j := range vs {
+			vs[j] = url.PathEscape(vs[j])
+		}
+		segs[i] = strings.Join(vs, "/")
+	}
+	if n < len(params) {
+		return "", fmt.Errorf("horo: too many params for path %q", path)
+	}
+	return strings.Join(segs, "/"), nil
+}
@@ -0,0 +1,148 @@
+package horo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/kylelemons/godebug/pretty"
+	"golang.org/x/net/context"
+)
+
+func TestReverse(t *testing.T) {
+	h := New()
+	nop := func(c context.Context) error { return nil }
+	h.GET("/users/:id/posts/:post", nop).Name("post")
+	h.Group("/static").GET("/*filepath", nop).Name("static")
+	h.GET("/", nop).Name("index")
+
+	tests := []struct {
+		name   string
+		params []interface{}
+		want   string
+		err    bool
+	}{
+		{"index", nil, "/", false},
+		{"post", []interface{}{1, "a b"}, "/users/1/posts/a%20b", false},
+		{"post", []interface{}{"a/b", 2}, "/users/a%2Fb/posts/2", false},
+		{"static", []interface{}{"/css/a b.css"}, "/static/css/a%20b.css", false},
+		{"post", []interface{}{1}, "", true},
+		{"index", []interface{}{1}, "", true},
+		{"unknown", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := h.Reverse(tt.name, tt.params...)
+		if (err != nil) != tt.err {
+			t.Errorf("Reverse(%q, %v) err = %v", tt.name, tt.params, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Reverse(%q, %v) = %v; want %v", tt.name, tt.params, got, tt.want)
+		}
+	}
+}
+
+func TestURL(t *testing.T) {
+	h := New()
+	h.GET("/users/:id", func(c context.Context) error {
+		return nil
+	}).Name("user")
+	h.GET("/", func(c context.Context) error {
+		u, err := URL(c, "user", "me")
+		if err != nil {
+			return err
+		}
+		return Redirect(c, http.StatusFound, u)
+	})
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if loc, want := w.Header().Get("Location"), "/users/me"; loc != want {
+		t.Errorf("Location = %v; want %v", loc, want)
+	}
+
+	if _, err := URL(context.Background(), "user"); err != ErrNotContext {
+		t.Errorf("err = %v; want %v", err, ErrNotContext)
+	}
+}
+
+func routesTestHandler(c context.Context) error {
+	return nil
+}
+
+func TestRoutes(t *testing.T) {
+	mw := func(next HandlerFunc) HandlerFunc { return next }
+	h := New()
+	h.Use(mw)
+	h.GET("/", routesTestHandler).Name("index")
+	h.Group("/api", mw).POST("/users", routesTestHandler, mw)
+	h.Group("/app", mw).FileSystem("/", fstest.MapFS{}, Fallback())
+	h.Mount("/", http.NotFoundHandler(), mw)
+
+	want := []RouteInfo{
+		{Method: "GET", Path: "/", Name: "index", Handler: "github.com/k2wanko/horo.routesTestHandler", Middleware: 1},
+		{Method: "POST", Path: "/api/users", Handler: "github.com/k2wanko/horo.routesTestHandler", Middleware: 3},
+		{Method: "*", Path: "/app/", Handler: "github.com/k2wanko/horo.(*fallback).serve-fm", Middleware: 2},
+		{Method: "*", Path: "/", Handler: "github.com/k2wanko/horo.WrapHandler.func1", Middleware: 2},
+	}
+	if diff := pretty.Compare(h.Routes(), want); diff != "" {
+		t.Errorf("diff:\n%s", diff)
+	}
+}
+
+func TestConstraints(t *testing.T) {
+	h := New()
+	h.GET("/users/:id{int}", func(c context.Context) error {
+		return Text(c, 200, "user "+Param(c, "id"))
+	}).Name("user")
+	h.GET("/posts/:slug{[a-z-]+}/:id{uuid}", func(c context.Context) error {
+		return Text(c, 200, Param(c, "slug"))
+	})
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/users/42", 200, "user 42"},
+		{"/users/abc", 404, "Not Found"},
+		{"/posts/hello-world/f47ac10b-58cc-4372-a567-0e02b2c3d479", 200, "hello-world"},
+		{"/posts/Hello/f47ac10b-58cc-4372-a567-0e02b2c3d479", 404, "Not Found"},
+		{"/posts/hello/1", 404, "Not Found"},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", tt.path, nil)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if code := w.Code; code != tt.code {
+			t.Errorf("%s: w.Code = %v; want %v", tt.path, code, tt.code)
+		}
+
+		if body := w.Body.String(); body != tt.body {
+			t.Errorf("%s: w.Body = %v; want %v", tt.path, body, tt.body)
+		}
+	}
+
+	routes := h.Routes()
+	if p, want := routes[0].Path, "/users/:id"; p != want {
+		t.Errorf("Path = %v; want %v", p, want)
+	}
+	if diff := pretty.Compare(routes[1].Constraints, map[string]string{"slug": "[a-z-]+", "id": "uuid"}); diff != "" {
+		t.Errorf("diff:\n%s", diff)
+	}
+
+	if u, err := h.Reverse("user", 1); err != nil || u != "/users/1" {
+		t.Errorf("Reverse = %v, %v; want /users/1", u, err)
+	}
+	if _, err := h.Reverse("user", "abc"); err == nil {
+		t.Errorf("Reverse with invalid param: err is nil")
+	}
+}
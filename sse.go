@@ -0,0 +1,196 @@
+package horo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+type (
+	// EventStream is Server-Sent Events writer returned by SSE.
+	EventStream struct {
+		w      *ResponseWriter
+		r      *http.Request
+		mu     sync.Mutex
+		closed bool
+		done   chan struct{}
+		wg     sync.WaitGroup
+	}
+
+	// SSEOption is SSE option.
+	SSEOption func(*sseOpts)
+
+	sseOpts struct {
+		heartbeat time.Duration
+	}
+)
+
+var (
+	// DefaultHeartbeat is interval of heartbeat comments sent by EventStream.
+	DefaultHeartbeat = 15 * time.Second
+
+	// ErrStreamClosed is thrown if EventStream is closed.
+	ErrStreamClosed = errors.New("event stream closed")
+
+	// ErrInvalidEventField is thrown if event or id contains a newline.
+	ErrInvalidEventField = errors.New("event field contains newline")
+)
+
+// Heartbeat sets heartbeat interval. Zero disables heartbeat.
+func Heartbeat(d time.Duration) SSEOption {
+	return func(o *sseOpts) {
+		o.heartbeat = d
+	}
+}
+
+// SSE starts a Server-Sent Events response.
+// The stream is closed when the client disconnects or the handler returns.
+func SSE(c context.Context, opt ...SSEOption) (*EventStream, error) {
+	hc := fromCtx(c)
+	if hc == nil {
+		return nil, ErrNotContext
+	}
+
+	o := &sseOpts{heartbeat: DefaultHeartbeat}
+	for _, f := range opt {
+		f(o)
+	}
+
+	h := hc.w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+	hc.w.WriteHeader(http.StatusOK)
+	hc.w.Flush()
+
+	es := &EventStream{
+		w:    hc.w,
+		r:    hc.r,
+		done: make(chan struct{}),
+	}
+	hc.onFinish(es.Close)
+
+	es.wg.Add(1)
+	go es.watch(c, o.heartbeat)
+
+	return es, nil
+}
+
+func (es *EventStream) watch(c context.Context, heartbeat time.Duration) {
+	defer es.wg.Done()
+
+	var tick <-chan time.Time
+	if heartbeat > 0 {
+		t := time.NewTicker(heartbeat)
+		defer t.Stop()
+		tick = t.C
+	}
+
+	for {
+		select {
+		case <-tick:
+			es.write([]byte(":\n\n"))
+		case <-es.r.Context().Done():
+			es.close()
+			return
+		case <-c.Done():
+			es.close()
+			return
+		case <-es.done:
+			return
+		}
+	}
+}
+
+// Done returns a channel closed when the stream is closed.
+func (es *EventStream) Done() <-chan struct{} {
+	return es.done
+}
+
+// LastEventID returns Last-Event-ID request header sent by a reconnecting client.
+func (es *EventStream) LastEventID() string {
+	return es.r.Header.Get("Last-Event-ID")
+}
+
+// Send sends an event. event and id are omitted if empty.
+// data is sent as is if string or []byte, otherwise encoded as JSON.
+func (es *EventStream) Send(event, id string, data interface{}) error {
+	if strings.ContainsAny(event, "\r\n") || strings.ContainsAny(id, "\r\n\x00") {
+		return ErrInvalidEventField
+	}
+
+	var d []byte
+	switch v := data.(type) {
+	case string:
+		d = []byte(v)
+	case []byte:
+		d = v
+	default:
+		b := getBuffer()
+		defer putBuffer(b)
+		if err := encodeJSONTo(b, v, ""); err != nil {
+			return err
+		}
+		d = b.Bytes()
+	}
+
+	b := getBuffer()
+	defer putBuffer(b)
+	if event != "" {
+		fmt.Fprintf(b, "event: %s\n", event)
+	}
+	if id != "" {
+		fmt.Fprintf(b, "id: %s\n", id)
+	}
+	// CRLF, CR and LF are all line breaks in an event stream.
+	d = bytes.ReplaceAll(d, []byte("\r\n"), []byte("\n"))
+	d = bytes.ReplaceAll(d, []byte("\r"), []byte("\n"))
+	for _, line := range bytes.Split(d, []byte("\n")) {
+		b.WriteString("data: ")
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	b.WriteByte('\n')
+	return es.write(b.Bytes())
+}
+
+// Retry sends the reconnection time hint.
+func (es *EventStream) Retry(d time.Duration) error {
+	return es.write([]byte("retry: " + strconv.FormatInt(int64(d/time.Millisecond), 10) + "\n\n"))
+}
+
+// Close closes the stream and stops heartbeat.
+func (es *EventStream) Close() {
+	es.close()
+	es.wg.Wait()
+}
+
+func (es *EventStream) close() {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if !es.closed {
+		es.closed = true
+		close(es.done)
+	}
+}
+
+func (es *EventStream) write(b []byte) error {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	if es.closed {
+		return ErrStreamClosed
+	}
+	if _, err := es.w.Write(b); err != nil {
+		return err
+	}
+	es.w.Flush()
+	return nil
+}
@@ -0,0 +1,107 @@
+package horo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestSSE(t *testing.T) {
+	var lastID string
+	h := New()
+	h.GET("/events", func(c context.Context) error {
+		es, err := SSE(c, Heartbeat(0))
+		if err != nil {
+			return err
+		}
+		lastID = es.LastEventID()
+		if err := es.Retry(3 * time.Second); err != nil {
+			return err
+		}
+		if err := es.Send("progress", "1", map[string]int{"done": 50}); err != nil {
+			return err
+		}
+		if err := es.Send("", "", "line1\nline2"); err != nil {
+			return err
+		}
+		if err := es.Send("", "", "hi\revent: evil\r\nend"); err != nil {
+			return err
+		}
+		if err := es.Send("bad\nevent", "", ""); err != ErrInvalidEventField {
+			t.Errorf("err = %v; want %v", err, ErrInvalidEventField)
+		}
+		return nil
+	})
+
+	r, _ := http.NewRequest("GET", "/events", nil)
+	r.Header.Set("Last-Event-ID", "42")
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if ct, want := w.Header().Get("Content-Type"), "text/event-stream"; ct != want {
+		t.Errorf("Content-Type = %v; want %v", ct, want)
+	}
+
+	if !w.Flushed {
+		t.Errorf("response is not flushed")
+	}
+
+	if want := "42"; lastID != want {
+		t.Errorf("LastEventID() = %v; want %v", lastID, want)
+	}
+
+	want := "retry: 3000\n\n" +
+		"event: progress\nid: 1\ndata: {\"done\":50}\n\n" +
+		"data: line1\ndata: line2\n\n" +
+		"data: hi\ndata: event: evil\ndata: end\n\n"
+	if body := w.Body.String(); body != want {
+		t.Errorf("w.Body = %q; want %q", body, want)
+	}
+}
+
+func TestSSEHeartbeatAndCancel(t *testing.T) {
+	var sendErr error
+	h := New()
+	h.GET("/events", func(c context.Context) error {
+		es, err := SSE(c, Heartbeat(5*time.Millisecond))
+		if err != nil {
+			return err
+		}
+		<-es.Done()
+		sendErr = es.Send("", "", "late")
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r, _ := http.NewRequest("GET", "/events", nil)
+	r = r.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		h.ServeHTTP(w, r)
+		close(done)
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handler did not return after the request was cancelled")
+	}
+
+	if sendErr != ErrStreamClosed {
+		t.Errorf("err = %v; want %v", sendErr, ErrStreamClosed)
+	}
+
+	if body := w.Body.String(); !strings.HasPrefix(body, ":\n\n") {
+		t.Errorf("w.Body = %q; want heartbeat comments", body)
+	}
+}
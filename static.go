@@ -0,0 +1,288 @@
+package horo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+type (
+	// StaticOption is Static and FileSystem option.
+	StaticOption func(*staticOpts)
+
+	staticOpts struct {
+		index   string
+		browse  bool
+		spa     bool
+		exclude []string
+	}
+
+	// fallback serves a single-page app for unknown paths under prefix,
+	// or files at the root. rt wraps the handler with the group middleware.
+	fallback struct {
+		prefix string
+		fsys   fs.FS
+		o      *staticOpts
+		rt     *Route
+	}
+)
+
+const staticParam = "filepath"
+
+// Index sets the file served for a directory. Default is index.html.
+func Index(name string) StaticOption {
+	return func(o *staticOpts) {
+		o.index = name
+	}
+}
+
+// Browse enables directory listing.
+func Browse() StaticOption {
+	return func(o *staticOpts) {
+		o.browse = true
+	}
+}
+
+// Fallback enables single-page app mode.
+// Unknown GET and HEAD paths under the prefix are served index file
+// instead of NotFound, except paths beginning with an exclude prefix.
+// Files are served through the NotFound middleware chain followed by the
+// group middleware, not as routes, so routes under the prefix still match.
+func Fallback(exclude ...string) StaticOption {
+	return func(o *staticOpts) {
+		o.spa = true
+		o.exclude = exclude
+	}
+}
+
+// Static serves files from the root directory under the prefix.
+func (h *Horo) Static(prefix, root string, opt ...StaticOption) {
+	h.FileSystem(prefix, os.DirFS(root), opt...)
+}
+
+// FileSystem serves files from fsys under the prefix.
+// Files at the root are served for paths no route matches.
+func (h *Horo) FileSystem(prefix string, fsys fs.FS, opt ...StaticOption) {
+	h.fileSystem(nil, prefix, fsys, opt...)
+}
+
+func (h *Horo) fileSystem(g *Group, prefix string, fsys fs.FS, opt ...StaticOption) {
+	o := &staticOpts{index: "index.html"}
+	for _, f := range opt {
+		f(o)
+	}
+
+	prefix = strings.TrimSuffix(prefix, "/")
+	if o.spa {
+		fb := &fallback{
+			prefix: prefix,
+			fsys:   fsys,
+			o:      o,
+		}
+		// Global middleware is already applied by the NotFound chain.
+		fb.rt = &Route{h: h, group: g, method: "*", path: prefix + "/", hf: fb.serve}
+		fb.rt.build(nil)
+		h.fallbacks = append(h.fallbacks, fb)
+		return
+	}
+
+	if prefix == "" {
+		// A catch-all at the root conflicts with every other route.
+		fb := &fallback{fsys: fsys, o: o}
+		fb.rt = &Route{h: h, group: g, method: "*", path: "/", hf: func(c context.Context) error {
+			return serveFSPath(c, fsys, o, Request(c).URL.Path)
+		}}
+		fb.rt.build(nil)
+		h.fallbacks = append(h.fallbacks, fb)
+		return
+	}
+
+	p := prefix + "/*" + staticParam
+	hf := serveFS(fsys, o)
+	h.add(g, http.MethodGet, p, hf)
+	h.add(g, http.MethodHead, p, hf)
+}
+
+// Static serves files from the root directory under the prefix.
+func (g *Group) Static(prefix, root string, opt ...StaticOption) {
+	g.FileSystem(prefix, os.DirFS(root), opt...)
+}
+
+// FileSystem serves files from fsys under the prefix.
+func (g *Group) FileSystem(prefix string, fsys fs.FS, opt ...StaticOption) {
+	g.h.fileSystem(g, g.prefix+prefix, fsys, opt...)
+}
+
+func serveFS(fsys fs.FS, o *staticOpts) HandlerFunc {
+	return func(c context.Context) error {
+		return serveFSPath(c, fsys, o, Param(c, staticParam))
+	}
+}
+
+func serveFSPath(c context.Context, fsys fs.FS, o *staticOpts, upath string) error {
+	hc := fromCtx(c)
+	if hc == nil {
+		return ErrNotContext
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+upath), "/")
+	if name == "" {
+		name = "."
+	}
+	if !fs.ValidPath(name) || strings.Contains(name, "\\") {
+		return hc.h.NotFound(c)
+	}
+
+	f, fi, err := openFile(fsys, name)
+	if err != nil {
+		return fsError(c, err)
+	}
+	defer f.Close()
+
+	if fi.IsDir() {
+		if !strings.HasSuffix(upath, "/") {
+			u := *hc.r.URL
+			u.Path += "/"
+			return Redirect(c, http.StatusMovedPermanently, u.RequestURI())
+		}
+
+		idx, ifi, err := openFile(fsys, path.Join(name, o.index))
+		if err == nil && !ifi.IsDir() {
+			defer idx.Close()
+			return serveFile(c, idx, ifi)
+		}
+
+		if o.browse {
+			return dirList(c, fsys, name)
+		}
+		return hc.h.NotFound(c)
+	}
+
+	return serveFile(c, f, fi)
+}
+
+// handleFallback serves a single-page app or files at the root if the path
+// matches, or the handler mounted at the root, otherwise NotFound.
+func (h *Horo) handleFallback(c context.Context) error {
+	r := Request(c)
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		for _, fb := range h.fallbacks {
+			if fb.match(r.URL.Path) {
+				return fb.rt.chain(c)
+			}
+		}
+	}
+	if h.mounted != nil {
+		for _, m := range mountMethods {
+			if r.Method == m {
+				return h.mounted.chain(c)
+			}
+		}
+	}
+	return h.NotFound(c)
+}
+
+func (fb *fallback) match(p string) bool {
+	if p != fb.prefix && !strings.HasPrefix(p, fb.prefix+"/") {
+		return false
+	}
+	for _, ex := range fb.o.exclude {
+		if strings.HasPrefix(p, ex) {
+			return false
+		}
+	}
+	return true
+}
+
+func (fb *fallback) serve(c context.Context) error {
+	upath := strings.TrimPrefix(Request(c).URL.Path, fb.prefix)
+	name := strings.TrimPrefix(path.Clean("/"+upath), "/")
+	if name != "" && fs.ValidPath(name) {
+		if f, fi, err := openFile(fb.fsys, name); err == nil {
+			defer f.Close()
+			if !fi.IsDir() {
+				return serveFile(c, f, fi)
+			}
+		}
+	}
+
+	f, fi, err := openFile(fb.fsys, fb.o.index)
+	if err != nil {
+		return fsError(c, err)
+	}
+	defer f.Close()
+	return serveFile(c, f, fi)
+}
+
+func openFile(fsys fs.FS, name string) (fs.File, fs.FileInfo, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, fi, nil
+}
+
+func fsError(c context.Context, err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if hc := fromCtx(c); hc != nil {
+			return hc.h.NotFound(c)
+		}
+		return NotFound(c)
+	case errors.Is(err, fs.ErrPermission):
+		return &HTTPError{
+			Code:    http.StatusForbidden,
+			Message: http.StatusText(http.StatusForbidden),
+		}
+	}
+	return err
+}
+
+func serveFile(c context.Context, f fs.File, fi fs.FileInfo) error {
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		rs = bytes.NewReader(b)
+	}
+	http.ServeContent(Response(c), Request(c), fi.Name(), fi.ModTime(), rs)
+	return nil
+}
+
+func dirList(c context.Context, fsys fs.FS, name string) error {
+	ents, err := fs.ReadDir(fsys, name)
+	if err != nil {
+		return fsError(c, err)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("<pre>\n")
+	for _, e := range ents {
+		n := e.Name()
+		if e.IsDir() {
+			n += "/"
+		}
+		u := url.URL{Path: n}
+		fmt.Fprintf(&b, "<a href=\"%s\">%s</a>\n", u.String(), html.EscapeString(n))
+	}
+	b.WriteString("</pre>\n")
+
+	return HTML(c, http.StatusOK, b.String())
+}
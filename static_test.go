@@ -0,0 +1,258 @@
+package horo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestFileSystem(t *testing.T) {
+	modTime := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"index.html":      {Data: []byte("<h1>index</h1>"), ModTime: modTime},
+		"css/app.css":     {Data: []byte("body{}"), ModTime: modTime},
+		"js/app.js":       {Data: []byte("alert(1)"), ModTime: modTime},
+		"docs/index.html": {Data: []byte("docs"), ModTime: modTime},
+	}
+
+	h := New()
+	h.FileSystem("/assets", fsys)
+	h.Group("/public").FileSystem("/", fsys, Browse())
+
+	tests := []struct {
+		path        string
+		code        int
+		body        string
+		contentType string
+	}{
+		{"/assets/", 200, "<h1>index</h1>", "text/html; charset=utf-8"},
+		{"/assets/css/app.css", 200, "body{}", "text/css; charset=utf-8"},
+		{"/assets/docs/", 200, "docs", "text/html; charset=utf-8"},
+		{"/assets/docs", 301, "", ""},
+		{"/assets/css/", 404, "Not Found", "text/plain"},
+		{"/assets/missing.js", 404, "Not Found", "text/plain"},
+		{"/assets/../static_test.go", 404, "Not Found", "text/plain"},
+		{"/public/css/", 200, "<pre>\n<a href=\"app.css\">app.css</a>\n</pre>\n", "text/html"},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", tt.path, nil)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if code := w.Code; code != tt.code {
+			t.Errorf("%s: w.Code = %v; want %v", tt.path, code, tt.code)
+		}
+
+		if tt.code == 301 {
+			continue
+		}
+
+		if body := w.Body.String(); body != tt.body {
+			t.Errorf("%s: w.Body = %v; want %v", tt.path, body, tt.body)
+		}
+
+		if ct := w.Header().Get("Content-Type"); ct != tt.contentType {
+			t.Errorf("%s: Content-Type = %v; want %v", tt.path, ct, tt.contentType)
+		}
+	}
+}
+
+func TestFileSystemModified(t *testing.T) {
+	modTime := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+	h := New()
+	h.FileSystem("/", fstest.MapFS{
+		"a.txt": {Data: []byte("a"), ModTime: modTime},
+	})
+
+	r, _ := http.NewRequest("GET", "/a.txt", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	lm := w.Header().Get("Last-Modified")
+	if want := modTime.Format(http.TimeFormat); lm != want {
+		t.Errorf("Last-Modified = %v; want %v", lm, want)
+	}
+
+	r, _ = http.NewRequest("GET", "/a.txt", nil)
+	r.Header.Set("If-Modified-Since", lm)
+	w = httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if code, want := w.Code, http.StatusNotModified; code != want {
+		t.Errorf("w.Code = %v; want %v", code, want)
+	}
+}
+
+func TestStatic(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "public")
+	if err := os.Mkdir(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "hello.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	h := New()
+	h.Static("/static", root)
+
+	for _, p := range []string{"/static/hello.txt", "/static/../secret.txt", "/static/%2e%2e/secret.txt"} {
+		r, _ := http.NewRequest("GET", p, nil)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if strings.Contains(w.Body.String(), "secret") {
+			t.Errorf("%s: served file outside root", p)
+		}
+		if p == "/static/hello.txt" && w.Body.String() != "hello" {
+			t.Errorf("%s: w.Body = %v; want hello", p, w.Body.String())
+		}
+	}
+}
+
+func TestFallback(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("spa")},
+		"app.js":     {Data: []byte("app")},
+	}
+
+	h := New()
+	h.NotFound = func(c context.Context) error {
+		return JSON(c, http.StatusNotFound, map[string]string{"error": "not found"})
+	}
+	h.GET("/api/users", func(c context.Context) error {
+		return Text(c, 200, "users")
+	})
+	h.FileSystem("/", fsys, Fallback("/api/"))
+
+	tests := []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{"GET", "/", 200, "spa"},
+		{"GET", "/app.js", 200, "app"},
+		{"GET", "/users/1/edit", 200, "spa"},
+		{"GET", "/api/users", 200, "users"},
+		{"GET", "/api/missing", 404, `{"error":"not found"}`},
+		{"POST", "/users", 404, `{"error":"not found"}`},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest(tt.method, tt.path, nil)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if code := w.Code; code != tt.code {
+			t.Errorf("%s %s: w.Code = %v; want %v", tt.method, tt.path, code, tt.code)
+		}
+
+		if body := w.Body.String(); body != tt.body {
+			t.Errorf("%s %s: w.Body = %v; want %v", tt.method, tt.path, body, tt.body)
+		}
+	}
+}
+
+func TestGroupFallback(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html": {Data: []byte("spa")},
+		"app.js":     {Data: []byte("app")},
+	}
+
+	h := New()
+	auth := func(next HandlerFunc) HandlerFunc {
+		return func(c context.Context) error {
+			if Request(c).Header.Get("Authorization") == "" {
+				return &HTTPError{Code: http.StatusUnauthorized, Message: "unauthorized"}
+			}
+			return next(c)
+		}
+	}
+	h.Group("/app", auth).FileSystem("/", fsys, Fallback())
+
+	tests := []struct {
+		path, auth string
+		code       int
+		body       string
+	}{
+		{"/app/app.js", "", 401, "unauthorized"},
+		{"/app/users/1", "", 401, "unauthorized"},
+		{"/app/app.js", "token", 200, "app"},
+		{"/app/users/1", "token", 200, "spa"},
+		{"/other", "", 404, http.StatusText(404)},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", tt.path, nil)
+		if tt.auth != "" {
+			r.Header.Set("Authorization", tt.auth)
+		}
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if code := w.Code; code != tt.code {
+			t.Errorf("%s: w.Code = %v; want %v", tt.path, code, tt.code)
+		}
+
+		if body := w.Body.String(); body != tt.body {
+			t.Errorf("%s: w.Body = %v; want %v", tt.path, body, tt.body)
+		}
+	}
+}
+
+func TestFileSystemRoot(t *testing.T) {
+	fsys := fstest.MapFS{
+		"index.html":  {Data: []byte("index")},
+		"css/app.css": {Data: []byte("body{}")},
+	}
+
+	h := New()
+	h.GET("/api/x", func(c context.Context) error {
+		return Text(c, 200, "x")
+	})
+	h.FileSystem("/", fsys)
+
+	tests := []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{"GET", "/", 200, "index"},
+		{"GET", "/css/app.css", 200, "body{}"},
+		{"GET", "/api/x", 200, "x"},
+		{"GET", "/missing", 404, "Not Found"},
+		{"POST", "/css/app.css", 404, "Not Found"},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest(tt.method, tt.path, nil)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if code := w.Code; code != tt.code {
+			t.Errorf("%s %s: w.Code = %v; want %v", tt.method, tt.path, code, tt.code)
+		}
+
+		if body := w.Body.String(); body != tt.body {
+			t.Errorf("%s %s: w.Body = %v; want %v", tt.method, tt.path, body, tt.body)
+		}
+	}
+}
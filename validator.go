@@ -0,0 +1,244 @@
+package horo
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+type (
+	// Validator validates a bound value.
+	Validator interface {
+		Validate(v interface{}) error
+	}
+
+	// ValidationError is returned by Validator with per-field messages.
+	ValidationError struct {
+		Fields map[string]string
+	}
+
+	tagValidator struct {
+		regexps sync.Map
+	}
+)
+
+var (
+	// DefaultValidator is tag based Validator.
+	//
+	// Rules are set by the `validate` struct tag separated by comma:
+	//   required      value is not zero
+	//   min=n, max=n  number value, or length of string, slice and map
+	//   len=n         length of string, slice and map
+	//   email         string is an email address
+	//   oneof=a b c   value is one of space separated values
+	//   regexp=re     string matches re. It must be the last rule.
+	// Rules except required are skipped for zero values.
+	// Fields are named by the json tag if present.
+	DefaultValidator Validator = &tagValidator{}
+)
+
+func (e *ValidationError) Error() string {
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	msgs := make([]string, len(keys))
+	for i, k := range keys {
+		msgs[i] = k + " " + e.Fields[k]
+	}
+	return "validation failed: " + strings.Join(msgs, ", ")
+}
+
+func (tv *tagValidator) Validate(v interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	fields := map[string]string{}
+	if err := tv.validateStruct(rv, "", fields); err != nil {
+		return err
+	}
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+func (tv *tagValidator) validateStruct(rv reflect.Value, prefix string, fields map[string]string) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+
+		fv := rv.Field(i)
+		name := prefix + fieldName(sf)
+
+		if tag := sf.Tag.Get("validate"); tag != "" && tag != "-" {
+			msg, err := tv.validateField(fv, tag)
+			if err != nil {
+				return fmt.Errorf("horo: field %s: %v", name, err)
+			}
+			if msg != "" {
+				fields[name] = msg
+				continue
+			}
+		}
+
+		if fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Struct {
+			p := name + "."
+			if sf.Anonymous {
+				p = prefix
+			}
+			if err := tv.validateStruct(fv, p, fields); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func fieldName(sf reflect.StructField) string {
+	if n := strings.Split(sf.Tag.Get("json"), ",")[0]; n != "" && n != "-" {
+		return n
+	}
+	return sf.Name
+}
+
+// validateField returns a failure message, or an error for an invalid rule.
+func (tv *tagValidator) validateField(fv reflect.Value, tag string) (string, error) {
+	zero := fv.IsZero()
+	if fv.Kind() == reflect.Ptr && !fv.IsNil() {
+		fv = fv.Elem()
+	}
+
+	for tag != "" {
+		var rule string
+		if strings.HasPrefix(tag, "regexp=") {
+			rule, tag = tag, ""
+		} else if i := strings.IndexByte(tag, ','); i >= 0 {
+			rule, tag = tag[:i], tag[i+1:]
+		} else {
+			rule, tag = tag, ""
+		}
+
+		name, arg := rule, ""
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			name, arg = rule[:i], rule[i+1:]
+		}
+
+		if name == "required" {
+			if zero {
+				return "is required", nil
+			}
+			continue
+		}
+		if zero {
+			continue
+		}
+
+		switch name {
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				return "", fmt.Errorf("invalid %s rule %q", name, arg)
+			}
+			v, isLen, ok := measure(fv, name == "len")
+			if !ok {
+				return "", fmt.Errorf("%s rule is not supported for %s", name, fv.Type())
+			}
+			switch {
+			case name == "min" && v < n && isLen:
+				return fmt.Sprintf("must be at least %s in length", arg), nil
+			case name == "min" && v < n:
+				return fmt.Sprintf("must be at least %s", arg), nil
+			case name == "max" && v > n && isLen:
+				return fmt.Sprintf("must be at most %s in length", arg), nil
+			case name == "max" && v > n:
+				return fmt.Sprintf("must be at most %s", arg), nil
+			case name == "len" && v != n:
+				return fmt.Sprintf("must be %s in length", arg), nil
+			}
+		case "email":
+			if fv.Kind() != reflect.String {
+				return "", fmt.Errorf("email rule is not supported for %s", fv.Type())
+			}
+			if a, err := mail.ParseAddress(fv.String()); err != nil || a.Address != fv.String() {
+				return "must be a valid email address", nil
+			}
+		case "oneof":
+			s := fmt.Sprint(fv.Interface())
+			found := false
+			for _, o := range strings.Fields(arg) {
+				if s == o {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Sprintf("must be one of [%s]", arg), nil
+			}
+		case "regexp":
+			if fv.Kind() != reflect.String {
+				return "", fmt.Errorf("regexp rule is not supported for %s", fv.Type())
+			}
+			re, err := tv.regexp(arg)
+			if err != nil {
+				return "", err
+			}
+			if !re.MatchString(fv.String()) {
+				return fmt.Sprintf("must match %s", arg), nil
+			}
+		default:
+			return "", fmt.Errorf("unknown rule %q", name)
+		}
+	}
+	return "", nil
+}
+
+// measure returns the number value or length of v.
+func measure(v reflect.Value, length bool) (n float64, isLen, ok bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true, true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true, true
+	}
+	if length {
+		return 0, false, false
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false, true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false, true
+	}
+	return 0, false, false
+}
+
+func (tv *tagValidator) regexp(expr string) (*regexp.Regexp, error) {
+	if re, ok := tv.regexps.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	tv.regexps.Store(expr, re)
+	return re, nil
+}
@@ -0,0 +1,135 @@
+package horo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kylelemons/godebug/pretty"
+	"golang.org/x/net/context"
+)
+
+type validateAddress struct {
+	Zip string `json:"zip" validate:"regexp=^[0-9]{3}-[0-9]{4}$"`
+}
+
+type validateUser struct {
+	Name    string           `json:"name" validate:"required,min=2,max=8"`
+	Email   string           `json:"email" validate:"required,email"`
+	Age     int              `json:"age" validate:"min=0,max=150"`
+	Role    string           `json:"role" validate:"oneof=admin user"`
+	Code    string           `json:"code" validate:"len=4"`
+	Tags    []string         `json:"tags" validate:"max=2"`
+	Address *validateAddress `json:"address"`
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		v    *validateUser
+		want map[string]string
+	}{
+		{
+			name: "valid",
+			v:    &validateUser{Name: "gopher", Email: "gopher@example.com", Role: "admin", Address: &validateAddress{Zip: "123-4567"}},
+		},
+		{
+			name: "required",
+			v:    &validateUser{},
+			want: map[string]string{"name": "is required", "email": "is required"},
+		},
+		{
+			name: "rules",
+			v: &validateUser{
+				Name:    "g",
+				Email:   "gopher",
+				Age:     200,
+				Role:    "root",
+				Code:    "12345",
+				Tags:    []string{"a", "b", "c"},
+				Address: &validateAddress{Zip: "1234567"},
+			},
+			want: map[string]string{
+				"name":        "must be at least 2 in length",
+				"email":       "must be a valid email address",
+				"age":         "must be at most 150",
+				"role":        "must be one of [admin user]",
+				"code":        "must be 4 in length",
+				"tags":        "must be at most 2 in length",
+				"address.zip": "must match ^[0-9]{3}-[0-9]{4}$",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		err := DefaultValidator.Validate(tt.v)
+		if tt.want == nil {
+			if err != nil {
+				t.Errorf("%s: err = %v", tt.name, err)
+			}
+			continue
+		}
+
+		ve, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("%s: err = %v; want *ValidationError", tt.name, err)
+			continue
+		}
+
+		if diff := pretty.Compare(ve.Fields, tt.want); diff != "" {
+			t.Errorf("%s: diff:\n%s", tt.name, diff)
+		}
+	}
+}
+
+func TestValidateInvalidRule(t *testing.T) {
+	v := struct {
+		N int `validate:"email"`
+	}{1}
+
+	if err := DefaultValidator.Validate(&v); err == nil {
+		t.Errorf("err is nil")
+	} else if _, ok := err.(*ValidationError); ok {
+		t.Errorf("err = %v; want rule error", err)
+	}
+}
+
+func TestBindValidate(t *testing.T) {
+	h := New()
+	h.POST("/", func(c context.Context) error {
+		var u validateUser
+		return Bind(c, &u)
+	})
+
+	tests := []struct {
+		accept string
+		body   string
+	}{
+		{"application/json", `{"fields":{"email":"is required","name":"is required"},"message":"Unprocessable Entity"}`},
+		{"", "validation failed: email is required, name is required"},
+		{"application/json;q=0, text/plain", "validation failed: email is required, name is required"},
+		{"*/*", "validation failed: email is required, name is required"},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest("POST", "/", strings.NewReader("{}"))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("Accept", tt.accept)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if code, want := w.Code, http.StatusUnprocessableEntity; code != want {
+			t.Errorf("Accept %q: w.Code = %v; want %v", tt.accept, code, want)
+		}
+
+		if body := w.Body.String(); body != tt.body {
+			t.Errorf("Accept %q: w.Body = %v; want %v", tt.accept, body, tt.body)
+		}
+
+		if vary, want := w.Header().Get("Vary"), "Accept"; vary != want {
+			t.Errorf("Accept %q: Vary = %v; want %v", tt.accept, vary, want)
+		}
+	}
+}
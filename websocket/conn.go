@@ -0,0 +1,479 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+type (
+	// Conn is WebSocket connection.
+	Conn struct {
+		conn        net.Conn
+		br          *bufio.Reader
+		isServer    bool
+		subprotocol string
+		compress    bool
+		maxSize     int64
+
+		// mmu serializes data messages, wmu serializes frames.
+		mmu       sync.Mutex
+		wmu       sync.Mutex
+		closeSent bool
+
+		readErr     error
+		pongHandler func(data []byte)
+	}
+
+	frame struct {
+		fin     bool
+		rsv1    bool
+		opcode  int
+		payload []byte
+	}
+
+	messageWriter struct {
+		c      *Conn
+		typ    int
+		buf    []byte
+		first  bool
+		closed bool
+	}
+)
+
+const (
+	finBit  = 1 << 7
+	rsv1Bit = 1 << 6
+	rsv2Bit = 1 << 5
+	rsv3Bit = 1 << 4
+	maskBit = 1 << 7
+
+	maxControlPayload = 125
+
+	// DefaultMaxMessageSize is default limit of a received message.
+	DefaultMaxMessageSize = 32 << 20
+
+	writeFrameSize = 4096
+)
+
+// deflateTail is removed from compressed messages by RFC 7692.
+var deflateTail = []byte{0x00, 0x00, 0xff, 0xff}
+
+func newConn(conn net.Conn, br *bufio.Reader, isServer bool) *Conn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+	return &Conn{
+		conn:     conn,
+		br:       br,
+		isServer: isServer,
+		maxSize:  DefaultMaxMessageSize,
+	}
+}
+
+// Subprotocol returns the negotiated subprotocol.
+func (c *Conn) Subprotocol() string {
+	return c.subprotocol
+}
+
+// Compressed reports whether permessage-deflate is negotiated.
+func (c *Conn) Compressed() bool {
+	return c.compress
+}
+
+// RemoteAddr returns the remote network address.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// SetReadDeadline sets the read deadline of the underlying connection.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the write deadline of the underlying connection.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// SetPongHandler sets the function called with pong payloads.
+func (c *Conn) SetPongHandler(f func(data []byte)) {
+	c.pongHandler = f
+}
+
+// Close closes the underlying connection without a close frame.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// ReadMessage reads the next data message.
+// Ping frames are answered with pong, and a close frame is answered and
+// returned as *CloseError. Fragmented messages are reassembled.
+func (c *Conn) ReadMessage() (messageType int, p []byte, err error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+
+	var buf []byte
+	var compressed bool
+	for {
+		f, err := c.readFrame()
+		if err != nil {
+			return 0, nil, c.fail(err)
+		}
+
+		switch f.opcode {
+		case PingMessage:
+			if err := c.writeFrame(true, false, PongMessage, f.payload); err != nil && err != ErrCloseSent {
+				return 0, nil, c.fail(err)
+			}
+			continue
+		case PongMessage:
+			if c.pongHandler != nil {
+				c.pongHandler(f.payload)
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, c.handleClose(f.payload)
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, c.fail(&protocolError{CloseProtocolError, "expected continuation frame"})
+			}
+			if f.rsv1 && !c.compress {
+				return 0, nil, c.fail(&protocolError{CloseProtocolError, "unexpected RSV1 bit"})
+			}
+			messageType, compressed = f.opcode, f.rsv1
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, c.fail(&protocolError{CloseProtocolError, "unexpected continuation frame"})
+			}
+			if f.rsv1 {
+				return 0, nil, c.fail(&protocolError{CloseProtocolError, "unexpected RSV1 bit"})
+			}
+		default:
+			return 0, nil, c.fail(&protocolError{CloseProtocolError, "unknown opcode"})
+		}
+
+		if int64(len(buf)+len(f.payload)) > c.maxSize {
+			return 0, nil, c.fail(ErrMessageTooBig)
+		}
+		buf = append(buf, f.payload...)
+		if f.fin {
+			break
+		}
+	}
+
+	if compressed {
+		if buf, err = decompress(buf, c.maxSize); err != nil {
+			return 0, nil, c.fail(err)
+		}
+	}
+
+	if messageType == TextMessage && !utf8.Valid(buf) {
+		return 0, nil, c.fail(&protocolError{CloseInvalidFramePayloadData, "invalid UTF-8 in text message"})
+	}
+	return messageType, buf, nil
+}
+
+func (c *Conn) readFrame() (f frame, err error) {
+	var h [8]byte
+	if _, err = io.ReadFull(c.br, h[:2]); err != nil {
+		return
+	}
+
+	if h[0]&(rsv2Bit|rsv3Bit) != 0 {
+		return f, &protocolError{CloseProtocolError, "unexpected reserved bits"}
+	}
+	f.fin = h[0]&finBit != 0
+	f.rsv1 = h[0]&rsv1Bit != 0
+	f.opcode = int(h[0] & 0x0f)
+	masked := h[1]&maskBit != 0
+
+	n := int64(h[1] & 0x7f)
+	switch n {
+	case 126:
+		if _, err = io.ReadFull(c.br, h[:2]); err != nil {
+			return
+		}
+		n = int64(binary.BigEndian.Uint16(h[:2]))
+	case 127:
+		if _, err = io.ReadFull(c.br, h[:8]); err != nil {
+			return
+		}
+		n = int64(binary.BigEndian.Uint64(h[:8]))
+		if n < 0 {
+			return f, &protocolError{CloseProtocolError, "invalid payload length"}
+		}
+	}
+
+	if f.opcode >= CloseMessage {
+		if n > maxControlPayload || !f.fin || f.rsv1 {
+			return f, &protocolError{CloseProtocolError, "invalid control frame"}
+		}
+	}
+
+	if masked != c.isServer {
+		if c.isServer {
+			return f, &protocolError{CloseProtocolError, "client frame is not masked"}
+		}
+		return f, &protocolError{CloseProtocolError, "server frame is masked"}
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.br, key[:]); err != nil {
+			return
+		}
+	}
+
+	if n > c.maxSize {
+		return f, ErrMessageTooBig
+	}
+	f.payload = make([]byte, n)
+	if _, err = io.ReadFull(c.br, f.payload); err != nil {
+		return
+	}
+	if masked {
+		maskBytes(key, f.payload)
+	}
+	return
+}
+
+func (c *Conn) handleClose(p []byte) error {
+	code, text := CloseNoStatusReceived, ""
+	switch {
+	case len(p) == 1:
+		return c.fail(&protocolError{CloseProtocolError, "invalid close payload"})
+	case len(p) >= 2:
+		code = int(binary.BigEndian.Uint16(p))
+		text = string(p[2:])
+		if !validCloseCode(code) {
+			return c.fail(&protocolError{CloseProtocolError, "invalid close code"})
+		}
+		if !utf8.ValidString(text) {
+			return c.fail(&protocolError{CloseInvalidFramePayloadData, "invalid UTF-8 in close reason"})
+		}
+	}
+
+	reply := CloseNormalClosure
+	if code != CloseNoStatusReceived {
+		reply = code
+	}
+	c.WriteClose(reply, "")
+
+	c.readErr = &CloseError{Code: code, Text: text}
+	return c.readErr
+}
+
+// fail closes the connection for err and returns it for later reads.
+func (c *Conn) fail(err error) error {
+	switch e := err.(type) {
+	case *protocolError:
+		c.WriteClose(e.code, "")
+	default:
+		if err == ErrMessageTooBig {
+			c.WriteClose(CloseMessageTooBig, "")
+		}
+	}
+	c.readErr = err
+	return err
+}
+
+// WriteMessage writes a message of messageType.
+func (c *Conn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case TextMessage, BinaryMessage:
+	case CloseMessage, PingMessage, PongMessage:
+		if len(data) > maxControlPayload {
+			return &protocolError{CloseProtocolError, "control frame payload too long"}
+		}
+		return c.writeFrame(true, false, messageType, data)
+	default:
+		return ErrBadMessageType
+	}
+
+	c.mmu.Lock()
+	defer c.mmu.Unlock()
+
+	if c.compress {
+		b, err := compress(data)
+		if err != nil {
+			return err
+		}
+		return c.writeFrame(true, true, messageType, b)
+	}
+	return c.writeFrame(true, false, messageType, data)
+}
+
+// NextWriter returns a writer of a message of messageType.
+// Without compression the message is sent in fragments while writing.
+// The message is finished by Close.
+func (c *Conn) NextWriter(messageType int) (io.WriteCloser, error) {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return nil, ErrBadMessageType
+	}
+	c.mmu.Lock()
+	return &messageWriter{c: c, typ: messageType, first: true}, nil
+}
+
+// Ping writes a ping frame.
+func (c *Conn) Ping(data []byte) error {
+	return c.WriteMessage(PingMessage, data)
+}
+
+// WriteClose writes a close frame with code and reason.
+func (c *Conn) WriteClose(code int, reason string) error {
+	var p []byte
+	if code != CloseNoStatusReceived {
+		p = make([]byte, 2, 2+len(reason))
+		binary.BigEndian.PutUint16(p, uint16(code))
+		p = append(p, reason...)
+	}
+	if len(p) > maxControlPayload {
+		p = p[:maxControlPayload]
+	}
+	return c.writeFrame(true, false, CloseMessage, p)
+}
+
+func (c *Conn) writeFrame(fin, rsv1 bool, opcode int, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if c.closeSent {
+		return ErrCloseSent
+	}
+
+	h := make([]byte, 2, 14+len(payload))
+	h[0] = byte(opcode)
+	if fin {
+		h[0] |= finBit
+	}
+	if rsv1 {
+		h[0] |= rsv1Bit
+	}
+
+	n := len(payload)
+	switch {
+	case n <= 125:
+		h[1] = byte(n)
+	case n <= 0xffff:
+		h[1] = 126
+		h = append(h, byte(n>>8), byte(n))
+	default:
+		h[1] = 127
+		var l [8]byte
+		binary.BigEndian.PutUint64(l[:], uint64(n))
+		h = append(h, l[:]...)
+	}
+
+	if c.isServer {
+		h = append(h, payload...)
+	} else {
+		var key [4]byte
+		if _, err := rand.Read(key[:]); err != nil {
+			return err
+		}
+		h[1] |= maskBit
+		h = append(h, key[:]...)
+		i := len(h)
+		h = append(h, payload...)
+		maskBytes(key, h[i:])
+	}
+
+	if _, err := c.conn.Write(h); err != nil {
+		return err
+	}
+	if opcode == CloseMessage {
+		c.closeSent = true
+	}
+	return nil
+}
+
+func (w *messageWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, ErrCloseSent
+	}
+	w.buf = append(w.buf, p...)
+	if !w.c.compress {
+		for len(w.buf) > writeFrameSize {
+			if err := w.flush(false, w.buf[:writeFrameSize]); err != nil {
+				return 0, err
+			}
+			w.buf = w.buf[writeFrameSize:]
+		}
+	}
+	return len(p), nil
+}
+
+func (w *messageWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer w.c.mmu.Unlock()
+
+	if w.c.compress {
+		b, err := compress(w.buf)
+		if err != nil {
+			return err
+		}
+		return w.c.writeFrame(true, true, w.typ, b)
+	}
+	return w.flush(true, w.buf)
+}
+
+func (w *messageWriter) flush(fin bool, p []byte) error {
+	op := continuationFrame
+	if w.first {
+		op = w.typ
+		w.first = false
+	}
+	return w.c.writeFrame(fin, false, op, p)
+}
+
+func maskBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i&3]
+	}
+}
+
+func compress(p []byte) ([]byte, error) {
+	var b bytes.Buffer
+	fw, err := flate.NewWriter(&b, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fw.Write(p); err != nil {
+		return nil, err
+	}
+	if err := fw.Flush(); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), deflateTail), nil
+}
+
+func decompress(p []byte, limit int64) ([]byte, error) {
+	// The tail and a final empty block terminate the stream.
+	r := flate.NewReader(io.MultiReader(
+		bytes.NewReader(p),
+		bytes.NewReader(deflateTail),
+		bytes.NewReader([]byte{0x01, 0x00, 0x00, 0xff, 0xff}),
+	))
+	defer r.Close()
+
+	b, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, &protocolError{CloseInvalidFramePayloadData, "invalid compressed data"}
+	}
+	if int64(len(b)) > limit {
+		return nil, ErrMessageTooBig
+	}
+	return b, nil
+}
@@ -0,0 +1,137 @@
+package websocket
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFragmentedMessage(t *testing.T) {
+	s := newServer(t, echo)
+	conn, _ := dial(t, s, nil)
+
+	w, err := conn.NextWriter(BinaryMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := bytes.Repeat([]byte{0, 1, 2, 3}, writeFrameSize)
+	if _, err := w.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	typ, p, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ != BinaryMessage || !bytes.Equal(p, msg) {
+		t.Errorf("ReadMessage() = %d, %d bytes; want %d, %d bytes", typ, len(p), BinaryMessage, len(msg))
+	}
+}
+
+func TestPingPong(t *testing.T) {
+	s := newServer(t, echo)
+	conn, _ := dial(t, s, nil)
+
+	pong := make(chan string, 1)
+	conn.SetPongHandler(func(data []byte) {
+		pong <- string(data)
+	})
+
+	if err := conn.Ping([]byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteMessage(TextMessage, []byte("after")); err != nil {
+		t.Fatal(err)
+	}
+	if _, p, err := conn.ReadMessage(); err != nil || string(p) != "after" {
+		t.Fatalf("ReadMessage() = %q, %v; want %q, nil", p, err, "after")
+	}
+
+	select {
+	case data := <-pong:
+		if want := "hi"; data != want {
+			t.Errorf("pong = %q; want %q", data, want)
+		}
+	default:
+		t.Errorf("pong is not received")
+	}
+}
+
+func TestClose(t *testing.T) {
+	s := newServer(t, echo)
+	conn, _ := dial(t, s, nil)
+
+	if err := conn.WriteClose(CloseGoingAway, "bye"); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteMessage(TextMessage, []byte("late")); err != ErrCloseSent {
+		t.Errorf("err = %v; want %v", err, ErrCloseSent)
+	}
+
+	_, _, err := conn.ReadMessage()
+	ce, ok := err.(*CloseError)
+	if !ok || ce.Code != CloseGoingAway {
+		t.Errorf("err = %v; want close %d", err, CloseGoingAway)
+	}
+}
+
+func TestProtocolError(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []byte
+		code  int
+	}{
+		{"unmasked", []byte{0x81, 0x01, 'a'}, CloseProtocolError},
+		{"reserved bits", []byte{0xa1, 0x80, 0, 0, 0, 0}, CloseProtocolError},
+		{"unknown opcode", []byte{0x83, 0x80, 0, 0, 0, 0}, CloseProtocolError},
+		{"fragmented ping", []byte{0x09, 0x80, 0, 0, 0, 0}, CloseProtocolError},
+		{"continuation", []byte{0x80, 0x80, 0, 0, 0, 0}, CloseProtocolError},
+		{"invalid UTF-8", []byte{0x81, 0x81, 0, 0, 0, 0, 0xff}, CloseInvalidFramePayloadData},
+		{"invalid close code", []byte{0x88, 0x82, 0, 0, 0, 0, 0x03, 0xec}, CloseProtocolError},
+		{"too big", []byte{0x82, 0x84, 0, 0, 0, 0, 1, 2, 3, 4}, CloseMessageTooBig},
+	}
+
+	s := newServer(t, echo, MaxMessageSize(3))
+	for _, test := range tests {
+		conn, _ := dial(t, s, nil)
+		if _, err := conn.conn.Write(test.frame); err != nil {
+			t.Fatal(err)
+		}
+
+		_, _, err := conn.ReadMessage()
+		ce, ok := err.(*CloseError)
+		if !ok || ce.Code != test.code {
+			t.Errorf("%s: err = %v; want close %d", test.name, err, test.code)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	msg := []byte(strings.Repeat("horo ", 100))
+	b, err := compress(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) >= len(msg) {
+		t.Errorf("compressed %d bytes; want less than %d", len(b), len(msg))
+	}
+
+	p, err := decompress(b, int64(len(msg)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(p, msg) {
+		t.Errorf("decompress() = %q; want %q", p, msg)
+	}
+
+	if _, err := decompress(b, 10); err != ErrMessageTooBig {
+		t.Errorf("err = %v; want %v", err, ErrMessageTooBig)
+	}
+
+	if _, err := decompress([]byte{0xff, 0xff}, 10); err == nil {
+		t.Errorf("decompress() of invalid data is not failed")
+	}
+}
@@ -0,0 +1,217 @@
+package websocket
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/k2wanko/horo"
+	"github.com/k2wanko/horo/log"
+	"golang.org/x/net/context"
+)
+
+type (
+	// Handler is WebSocket connection handler.
+	// The connection is closed with CloseNormalClosure after Handler returns,
+	// or with CloseInternalServerErr if it returns an error.
+	Handler func(c context.Context, conn *Conn) error
+
+	// Option is WebSocket option.
+	Option func(*opts)
+
+	opts struct {
+		subprotocols []string
+		checkOrigin  func(r *http.Request) bool
+		compression  bool
+		maxSize      int64
+	}
+)
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Subprotocols sets the supported subprotocols in order of preference.
+func Subprotocols(p ...string) Option {
+	return func(o *opts) {
+		o.subprotocols = append(o.subprotocols, p...)
+	}
+}
+
+// CheckOrigin sets the function to accept the request Origin.
+// By default the Origin host must be the request host.
+func CheckOrigin(f func(r *http.Request) bool) Option {
+	return func(o *opts) {
+		o.checkOrigin = f
+	}
+}
+
+// Compression enables permessage-deflate if the client offers it.
+func Compression() Option {
+	return func(o *opts) {
+		o.compression = true
+	}
+}
+
+// MaxMessageSize sets the limit of a received message.
+func MaxMessageSize(n int64) Option {
+	return func(o *opts) {
+		o.maxSize = n
+	}
+}
+
+// WebSocket returns HandlerFunc upgrading the request to WebSocket.
+// A failed handshake returns *horo.HTTPError before the upgrade.
+func WebSocket(handler Handler, opt ...Option) horo.HandlerFunc {
+	o := &opts{
+		checkOrigin: sameOrigin,
+		maxSize:     DefaultMaxMessageSize,
+	}
+	for _, f := range opt {
+		f(o)
+	}
+
+	return func(c context.Context) error {
+		r, w := horo.Request(c), horo.Response(c)
+		if r == nil || w == nil {
+			return horo.ErrNotContext
+		}
+
+		conn, err := upgrade(w, r, o)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		// The response is hijacked, so errors can not be handled by horo.
+		if err := handler(c, conn); err != nil {
+			var ce *CloseError
+			if errors.As(err, &ce) {
+				return nil
+			}
+			log.FromContext(c).Errorf(c, "websocket: %v", err)
+			conn.WriteClose(CloseInternalServerErr, "")
+			return nil
+		}
+		conn.WriteClose(CloseNormalClosure, "")
+		return nil
+	}
+}
+
+func upgrade(w *horo.ResponseWriter, r *http.Request, o *opts) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		return nil, handshakeError(http.StatusMethodNotAllowed, "websocket: method is not GET")
+	}
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		return nil, handshakeError(http.StatusBadRequest, "websocket: not a websocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, handshakeError(http.StatusUpgradeRequired, "websocket: unsupported version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if b, err := base64.StdEncoding.DecodeString(key); err != nil || len(b) != 16 {
+		return nil, handshakeError(http.StatusBadRequest, "websocket: invalid Sec-WebSocket-Key")
+	}
+	if !o.checkOrigin(r) {
+		return nil, handshakeError(http.StatusForbidden, "websocket: origin not allowed")
+	}
+
+	subprotocol := selectSubprotocol(r.Header, o.subprotocols)
+	compress := o.compression && offersDeflate(r.Header)
+
+	nc, brw, err := w.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	b := []byte("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: ")
+	b = append(b, acceptKey(key)...)
+	if subprotocol != "" {
+		b = append(b, "\r\nSec-WebSocket-Protocol: "...)
+		b = append(b, subprotocol...)
+	}
+	if compress {
+		b = append(b, "\r\nSec-WebSocket-Extensions: permessage-deflate; server_no_context_takeover; client_no_context_takeover"...)
+	}
+	b = append(b, "\r\n\r\n"...)
+	if _, err := nc.Write(b); err != nil {
+		nc.Close()
+		return nil, err
+	}
+
+	conn := newConn(nc, brw.Reader, true)
+	conn.subprotocol = subprotocol
+	conn.compress = compress
+	conn.maxSize = o.maxSize
+	return conn, nil
+}
+
+func handshakeError(code int, msg string) *horo.HTTPError {
+	return &horo.HTTPError{Code: code, Message: msg}
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// headerContains reports whether the comma separated header has token.
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func selectSubprotocol(h http.Header, supported []string) string {
+	for _, s := range supported {
+		if headerContains(h, "Sec-WebSocket-Protocol", s) {
+			return s
+		}
+	}
+	return ""
+}
+
+// offersDeflate reports whether an acceptable permessage-deflate is offered.
+// Offers limiting the server window are declined since compress/flate
+// always uses the full window.
+func offersDeflate(h http.Header) bool {
+	for _, v := range h["Sec-Websocket-Extensions"] {
+		for _, ext := range strings.Split(v, ",") {
+			params := strings.Split(ext, ";")
+			if strings.TrimSpace(params[0]) != "permessage-deflate" {
+				continue
+			}
+			ok := true
+			for _, p := range params[1:] {
+				kv := strings.SplitN(strings.TrimSpace(p), "=", 2)
+				if kv[0] == "server_max_window_bits" && len(kv) == 2 && strings.Trim(kv[1], `"`) != "15" {
+					ok = false
+				}
+			}
+			if ok {
+				return true
+			}
+		}
+	}
+	return false
+}
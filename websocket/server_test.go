@@ -0,0 +1,162 @@
+package websocket
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/k2wanko/horo"
+	"golang.org/x/net/context"
+)
+
+func echo(c context.Context, conn *Conn) error {
+	for {
+		typ, p, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		if err := conn.WriteMessage(typ, p); err != nil {
+			return err
+		}
+	}
+}
+
+func newServer(t *testing.T, handler Handler, opt ...Option) *httptest.Server {
+	h := horo.New()
+	h.GET("/ws", WebSocket(handler, opt...))
+	s := httptest.NewServer(h)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// dial opens a client Conn to the server.
+func dial(t *testing.T, s *httptest.Server, header http.Header) (*Conn, *http.Response) {
+	nc, err := net.Dial("tcp", s.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { nc.Close() })
+
+	r, _ := http.NewRequest("GET", s.URL+"/ws", nil)
+	r.Header.Set("Connection", "Upgrade")
+	r.Header.Set("Upgrade", "websocket")
+	r.Header.Set("Sec-WebSocket-Version", "13")
+	r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	if err := r.Write(nc); err != nil {
+		t.Fatal(err)
+	}
+
+	br := bufio.NewReader(nc)
+	res, err := http.ReadResponse(br, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newConn(nc, br, false), res
+}
+
+func TestHandshake(t *testing.T) {
+	s := newServer(t, echo, Subprotocols("v2", "v1"), Compression())
+
+	conn, res := dial(t, s, http.Header{
+		"Sec-Websocket-Protocol":   {"v1, v2"},
+		"Sec-Websocket-Extensions": {"permessage-deflate; client_max_window_bits"},
+	})
+
+	if code, want := res.StatusCode, http.StatusSwitchingProtocols; code != want {
+		t.Fatalf("StatusCode = %d; want %d", code, want)
+	}
+
+	for k, want := range map[string]string{
+		"Sec-Websocket-Accept":     "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=",
+		"Sec-Websocket-Protocol":   "v2",
+		"Sec-Websocket-Extensions": "permessage-deflate; server_no_context_takeover; client_no_context_takeover",
+	} {
+		if v := res.Header.Get(k); v != want {
+			t.Errorf("%s = %q; want %q", k, v, want)
+		}
+	}
+
+	conn.compress = true
+	msg := strings.Repeat("hello ", 1000)
+	if err := conn.WriteMessage(TextMessage, []byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+	typ, p, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ != TextMessage || string(p) != msg {
+		t.Errorf("ReadMessage() = %d, %d bytes; want %d, %d bytes", typ, len(p), TextMessage, len(msg))
+	}
+}
+
+func TestHandshakeError(t *testing.T) {
+	h := horo.New()
+	h.GET("/ws", WebSocket(echo))
+
+	tests := []struct {
+		header http.Header
+		code   int
+	}{
+		{http.Header{}, http.StatusBadRequest},
+		{http.Header{
+			"Connection": {"keep-alive, Upgrade"},
+			"Upgrade":    {"websocket"},
+		}, http.StatusUpgradeRequired},
+		{http.Header{
+			"Connection":            {"Upgrade"},
+			"Upgrade":               {"websocket"},
+			"Sec-Websocket-Version": {"13"},
+			"Sec-Websocket-Key":     {"short"},
+		}, http.StatusBadRequest},
+		{http.Header{
+			"Connection":            {"Upgrade"},
+			"Upgrade":               {"websocket"},
+			"Sec-Websocket-Version": {"13"},
+			"Sec-Websocket-Key":     {"dGhlIHNhbXBsZSBub25jZQ=="},
+			"Origin":                {"http://evil.example.com"},
+		}, http.StatusForbidden},
+		{http.Header{
+			"Connection":            {"Upgrade"},
+			"Upgrade":               {"websocket"},
+			"Sec-Websocket-Version": {"13"},
+			"Sec-Websocket-Key":     {"dGhlIHNhbXBsZSBub25jZQ=="},
+		}, http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		r, _ := http.NewRequest("GET", "http://example.com/ws", nil)
+		r.Header = test.header
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		// httptest.ResponseRecorder does not support hijacking.
+		if code, want := w.Code, test.code; code != want {
+			t.Errorf("%v: w.Code = %d; want %d", test.header, code, want)
+		}
+	}
+}
+
+func TestHandlerClose(t *testing.T) {
+	s := newServer(t, func(c context.Context, conn *Conn) error {
+		return conn.WriteMessage(TextMessage, []byte("bye"))
+	})
+
+	conn, _ := dial(t, s, nil)
+	if _, p, err := conn.ReadMessage(); err != nil || string(p) != "bye" {
+		t.Fatalf("ReadMessage() = %q, %v; want %q, nil", p, err, "bye")
+	}
+
+	_, _, err := conn.ReadMessage()
+	ce, ok := err.(*CloseError)
+	if !ok || ce.Code != CloseNormalClosure {
+		t.Errorf("err = %v; want close %d", err, CloseNormalClosure)
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Package websocket is RFC 6455 WebSocket for horo.
+
+Example:
+
+	func Echo(c context.Context, conn *websocket.Conn) error {
+	    for {
+	        typ, p, err := conn.ReadMessage()
+	        if err != nil {
+	            return err
+	        }
+	        if err := conn.WriteMessage(typ, p); err != nil {
+	            return err
+	        }
+	    }
+	}
+
+	h.GET("/ws", websocket.WebSocket(Echo, websocket.Compression()))
+*/
+package websocket
+
+import (
+	"errors"
+	"fmt"
+)
+
+type (
+	// CloseError is returned by Conn when a close frame is received.
+	CloseError struct {
+		Code int
+		Text string
+	}
+)
+
+// Message types.
+const (
+	// TextMessage is UTF-8 text message.
+	TextMessage = 1
+
+	// BinaryMessage is binary data message.
+	BinaryMessage = 2
+
+	// CloseMessage is close control message.
+	CloseMessage = 8
+
+	// PingMessage is ping control message.
+	PingMessage = 9
+
+	// PongMessage is pong control message.
+	PongMessage = 10
+
+	continuationFrame = 0
+)
+
+// Close codes defined in RFC 6455 section 7.4.1.
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseMandatoryExtension      = 1010
+	CloseInternalServerErr       = 1011
+	CloseServiceRestart          = 1012
+	CloseTryAgainLater           = 1013
+	CloseTLSHandshake            = 1015
+)
+
+var (
+	// ErrCloseSent is thrown if a message is written after the close frame.
+	ErrCloseSent = errors.New("websocket: close sent")
+
+	// ErrMessageTooBig is thrown if a received message exceeds the limit.
+	ErrMessageTooBig = errors.New("websocket: message too big")
+
+	// ErrBadMessageType is thrown if WriteMessage is given an unknown type.
+	ErrBadMessageType = errors.New("websocket: bad message type")
+
+	closeText = map[int]string{
+		CloseNormalClosure:           "normal",
+		CloseGoingAway:               "going away",
+		CloseProtocolError:           "protocol error",
+		CloseUnsupportedData:         "unsupported data",
+		CloseNoStatusReceived:        "no status",
+		CloseAbnormalClosure:         "abnormal closure",
+		CloseInvalidFramePayloadData: "invalid payload data",
+		ClosePolicyViolation:         "policy violation",
+		CloseMessageTooBig:           "message too big",
+		CloseMandatoryExtension:      "mandatory extension missing",
+		CloseInternalServerErr:       "internal server error",
+		CloseServiceRestart:          "service restart",
+		CloseTryAgainLater:           "try again later",
+		CloseTLSHandshake:            "TLS handshake error",
+	}
+)
+
+func (e *CloseError) Error() string {
+	s := fmt.Sprintf("websocket: close %d", e.Code)
+	if t, ok := closeText[e.Code]; ok {
+		s += " (" + t + ")"
+	}
+	if e.Text != "" {
+		s += ": " + e.Text
+	}
+	return s
+}
+
+// protocolError is a violation closing the connection with Code.
+type protocolError struct {
+	code int
+	msg  string
+}
+
+func (e *protocolError) Error() string {
+	return "websocket: " + e.msg
+}
+
+// validCloseCode reports whether code may be sent in a close frame.
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1014:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package horo
+
+import (
+	"net/http"
+
+	"golang.org/x/net/context"
+)
+
+const mountParam = "horo_mount"
+
+// WrapHandler wraps http.Handler into HandlerFunc.
+// The request context carries the horo context, and the response is
+// written through ResponseWriter.
+func WrapHandler(hh http.Handler) HandlerFunc {
+	return func(c context.Context) error {
+		if hc := fromCtx(c); hc != nil {
+			hh.ServeHTTP(hc.w, hc.r.WithContext(c))
+			return nil
+		}
+		return ErrNotContext
+	}
+}
+
+// WrapHandlerFunc wraps http.HandlerFunc into HandlerFunc.
+func WrapHandlerFunc(f http.HandlerFunc) HandlerFunc {
+	return WrapHandler(f)
+}
+
+// WrapMiddleware wraps func(http.Handler) http.Handler middleware into MiddlewareFunc.
+// The *http.Request and http.ResponseWriter passed on by the middleware are
+// set to the horo context, so Request and Response reflect them.
+// An error returned by next is handled by ErrorHandler inside the middleware,
+// so the error response is written through its http.ResponseWriter.
+func WrapMiddleware(m func(http.Handler) http.Handler) MiddlewareFunc {
+	return func(next HandlerFunc) HandlerFunc {
+		hh := m(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c := r.Context()
+			hc := fromCtx(c)
+			if hc == nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+
+			rw, req := hc.w, hc.r
+			defer func() {
+				hc.w, hc.r, hc.query = rw, req, nil
+			}()
+
+			hc.r, hc.query = r, nil
+			if w != rw {
+				hc.w = &ResponseWriter{
+					rw:        w,
+					code:      rw.code,
+					committed: rw.committed,
+				}
+			}
+
+			if err := next(c); err != nil {
+				if hc.w.Committed() {
+					hc.h.committedError(c, hc.w, err)
+					return
+				}
+				hc.h.ErrorHandler(c, err)
+			}
+		}))
+
+		return func(c context.Context) error {
+			hc := fromCtx(c)
+			if hc == nil {
+				return ErrNotContext
+			}
+
+			hh.ServeHTTP(hc.w, hc.r.WithContext(c))
+			return nil
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package horo
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+func TestMount(t *testing.T) {
+	var status int
+	var size int64
+	h := New()
+	h.Use(func(next HandlerFunc) HandlerFunc {
+		return func(c context.Context) (err error) {
+			err = next(c)
+			status, size = Response(c).Status(), Response(c).Size()
+			return
+		}
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pprof/", func(w http.ResponseWriter, r *http.Request) {
+		if RequestID(r.Context()) == "" {
+			t.Errorf("request context does not have horo context")
+		}
+		w.WriteHeader(202)
+		w.Write([]byte(r.URL.Path))
+	})
+	h.Mount("/debug", mux)
+
+	sub := New()
+	sub.GET("/users/:id", func(c context.Context) error {
+		return Text(c, 200, Param(c, "id"))
+	})
+	h.Mount("/sub/", sub)
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/debug/pprof/heap", 202, "/pprof/heap"},
+		{"/sub/users/me", 200, "me"},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", tt.path, nil)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if code := w.Code; code != tt.code {
+			t.Errorf("%s: w.Code = %v; want %v", tt.path, code, tt.code)
+		}
+
+		if body := w.Body.String(); body != tt.body {
+			t.Errorf("%s: w.Body = %v; want %v", tt.path, body, tt.body)
+		}
+
+		if status != tt.code {
+			t.Errorf("%s: Status() = %v; want %v", tt.path, status, tt.code)
+		}
+
+		if want := int64(len(tt.body)); size != want {
+			t.Errorf("%s: Size() = %v; want %v", tt.path, size, want)
+		}
+	}
+}
+
+func TestWrapHandlerFunc(t *testing.T) {
+	h := New()
+	h.GET("/:name", WrapHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(Param(r.Context(), "name")))
+	}))
+
+	r, _ := http.NewRequest("GET", "/gopher", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if body, want := w.Body.String(), "gopher"; body != want {
+		t.Errorf("w.Body = %v; want %v", body, want)
+	}
+}
+
+type upperWriter struct {
+	http.ResponseWriter
+}
+
+func (w *upperWriter) Write(b []byte) (int, error) {
+	return w.ResponseWriter.Write(bytes.ToUpper(b))
+}
+
+func TestWrapMiddleware(t *testing.T) {
+	h := New()
+	h.Use(WrapMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Header.Set("X-Upstream", "yes")
+			next.ServeHTTP(&upperWriter{w}, r)
+		})
+	}))
+	h.GET("/", func(c context.Context) error {
+		return Text(c, 200, Request(c).Header.Get("X-Upstream"))
+	})
+	h.GET("/error", func(c context.Context) error {
+		return &HTTPError{Code: 400, Message: "bad"}
+	})
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/", 200, "YES"},
+		{"/error", 400, "BAD"},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", tt.path, nil)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if code := w.Code; code != tt.code {
+			t.Errorf("%s: w.Code = %v; want %v", tt.path, code, tt.code)
+		}
+
+		if body := w.Body.String(); body != tt.body {
+			t.Errorf("%s: w.Body = %v; want %v", tt.path, body, tt.body)
+		}
+	}
+}
+
+func TestWrapMiddlewareShortCircuit(t *testing.T) {
+	var status int
+	h := New()
+	h.GET("/", func(c context.Context) error {
+		t.Errorf("handler called")
+		return nil
+	}, func(next HandlerFunc) HandlerFunc {
+		return func(c context.Context) (err error) {
+			err = next(c)
+			status = Response(c).Status()
+			return
+		}
+	}, WrapMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}))
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, r)
+
+	if code, want := w.Code, 204; code != want {
+		t.Errorf("w.Code = %v; want %v", code, want)
+	}
+
+	if want := 204; status != want {
+		t.Errorf("Status() = %v; want %v", status, want)
+	}
+}
+
+type bufferWriter struct {
+	http.ResponseWriter
+	code int
+	buf  bytes.Buffer
+}
+
+func (w *bufferWriter) WriteHeader(code int) {
+	w.code = code
+}
+
+func (w *bufferWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return w.buf.Write(b)
+}
+
+func TestWrapMiddlewareBuffered(t *testing.T) {
+	h := New()
+	h.Use(WrapMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			bw := &bufferWriter{ResponseWriter: w}
+			next.ServeHTTP(bw, r)
+			if bw.code == 0 {
+				bw.code = http.StatusOK
+			}
+			w.WriteHeader(bw.code)
+			w.Write(bw.buf.Bytes())
+		})
+	}))
+	h.GET("/error", func(c context.Context) error {
+		return &HTTPError{Code: 400, Message: "bad"}
+	})
+	h.GET("/panic", func(c context.Context) error {
+		panic("boom")
+	})
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/error", 400, "bad"},
+		{"/panic", 500, http.StatusText(500)},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest("GET", tt.path, nil)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if code := w.Code; code != tt.code {
+			t.Errorf("%s: w.Code = %v; want %v", tt.path, code, tt.code)
+		}
+
+		if body := w.Body.String(); body != tt.body {
+			t.Errorf("%s: w.Body = %v; want %v", tt.path, body, tt.body)
+		}
+	}
+}
+
+func TestMountRoot(t *testing.T) {
+	var called bool
+	h := New()
+	h.GET("/", func(c context.Context) error {
+		return Text(c, 200, "index")
+	})
+
+	sub := New()
+	sub.GET("/users/:id", func(c context.Context) error {
+		return Text(c, 200, Param(c, "id"))
+	})
+	h.Group("", func(next HandlerFunc) HandlerFunc {
+		return func(c context.Context) error {
+			called = true
+			return next(c)
+		}
+	}).Mount("/", sub)
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{"GET", "/", 200, "index"},
+		{"GET", "/users/me", 200, "me"},
+		{"GET", "/missing", 404, http.StatusText(404)},
+		{"TRACE", "/users/me", 404, http.StatusText(404)},
+	}
+
+	for _, tt := range tests {
+		r, _ := http.NewRequest(tt.method, tt.path, nil)
+		w := httptest.NewRecorder()
+
+		h.ServeHTTP(w, r)
+
+		if code := w.Code; code != tt.code {
+			t.Errorf("%s %s: w.Code = %v; want %v", tt.method, tt.path, code, tt.code)
+		}
+
+		if body := w.Body.String(); body != tt.body {
+			t.Errorf("%s %s: w.Body = %v; want %v", tt.method, tt.path, body, tt.body)
+		}
+	}
+
+	if !called {
+		t.Errorf("group middleware not called")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Mount is not panicked")
+		}
+	}()
+	h.Mount("", sub)
+}